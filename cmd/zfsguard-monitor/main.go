@@ -10,6 +10,7 @@ import (
 	"github.com/pbek/zfsguard/internal/config"
 	"github.com/pbek/zfsguard/internal/monitor"
 	"github.com/pbek/zfsguard/internal/version"
+	"github.com/pbek/zfsguard/internal/zfs"
 )
 
 func main() {
@@ -28,7 +29,7 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	svc := monitor.New(cfg)
+	svc := monitor.New(cfg, zfs.ExecRunner{})
 
 	if *oneshot {
 		if err := svc.RunOnce(); err != nil {
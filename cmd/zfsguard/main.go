@@ -1,40 +1,60 @@
-// zfsguard is a TUI for managing ZFS snapshots.
+// zfsguard is a TUI for managing ZFS snapshots. With a subcommand such as
+// "list" or "health" it runs non-interactively instead.
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pbek/zfsguard/internal/cli"
 	"github.com/pbek/zfsguard/internal/config"
 	"github.com/pbek/zfsguard/internal/tui"
 	"github.com/pbek/zfsguard/internal/version"
+	"github.com/pbek/zfsguard/internal/zfs"
 )
 
 func main() {
-	// Simple flag parsing (avoid importing flag to keep startup fast)
-	var configPath string
-	args := os.Args[1:]
-	for i := 0; i < len(args); i++ {
-		switch args[i] {
-		case "--version", "-v":
-			fmt.Println(version.String("zfsguard"))
-			os.Exit(0)
-		case "--config":
-			if i+1 < len(args) {
-				i++
-				configPath = args[i]
-			}
+	flags := flag.NewFlagSet("zfsguard", flag.ContinueOnError)
+	configPath := flags.String("config", "", "Path to config file (default: auto-detect)")
+	showVersion := flags.Bool("version", false, "Print version and exit")
+	flags.BoolVar(showVersion, "v", false, "Print version and exit (shorthand)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: zfsguard [--config path] [command] [args]")
+		fmt.Fprintln(os.Stderr)
+		flags.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		cli.Usage(os.Stderr)
+	}
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(cli.ExitOK)
 		}
+		os.Exit(cli.ExitUsage)
+	}
+
+	if *showVersion {
+		fmt.Println(version.String("zfsguard"))
+		os.Exit(0)
 	}
 
-	cfg, err := config.Load(configPath)
+	cfg, err := config.Load(*configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to load config: %v\n", err)
 		cfg = config.DefaultConfig()
 	}
 
-	m := tui.NewModel(cfg.Monitor.ReportPath)
+	if args := flags.Args(); len(args) > 0 {
+		os.Exit(cli.Run(&cli.Env{
+			Config: cfg,
+			Runner: zfs.ExecRunner{},
+			Stdout: os.Stdout,
+			Stderr: os.Stderr,
+		}, args))
+	}
+
+	m := tui.NewModel(cfg, zfs.ExecRunner{})
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
 	if _, err := p.Run(); err != nil {
@@ -0,0 +1,131 @@
+// Package cli implements the non-interactive subcommands of zfsguard
+// (list, create, destroy, health, prune). Every command prints
+// tab-separated values by default and JSON with --json.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/zfs"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK      = 0 // success; for health: everything healthy
+	ExitFailure = 1 // the operation failed; for health: a problem was found
+	ExitUsage   = 2 // invalid arguments, or the command could not run at all
+)
+
+// Env is what a command runs against.
+type Env struct {
+	Config config.Config
+	Runner zfs.Runner
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(env *Env, args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"list", "list [--json] [-r] [dataset...]", "List snapshots", runList},
+		{"create", "create [-r] <dataset[@name]>...", "Create snapshots", runCreate},
+		{"destroy", "destroy [--dry-run] [--json] <snapshot>...", "Destroy snapshots", runDestroy},
+		{"health", "health [--json|--check] [--live]", "Show pool and disk health", runHealth},
+		{"health history", "health history [--json] [--since t] [--until t]", "Show when pool and disk states changed", runHealthHistory},
+		{"prune", "prune [--dry-run] [--json]", "Apply the retention rules", runPrune},
+	}
+}
+
+func lookup(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// Usage writes the list of subcommands to w.
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-48s %s\n", c.usage, c.summary)
+	}
+	fmt.Fprintln(w, "\nRun without a command to start the TUI.")
+}
+
+// Run executes the subcommand args[0] with the remaining arguments and
+// returns the process exit code.
+func Run(env *Env, args []string) int {
+	if len(args) == 0 || args[0] == "help" {
+		Usage(env.Stdout)
+		return ExitOK
+	}
+	c := lookup(args[0])
+	if c == nil {
+		fmt.Fprintf(env.Stderr, "zfsguard: unknown command %q\n\n", args[0])
+		Usage(env.Stderr)
+		return ExitUsage
+	}
+	return c.run(env, args[1:])
+}
+
+// newFlags creates the flag set of a subcommand. Parse errors are reported
+// by the caller, which exits with ExitUsage.
+func newFlags(env *Env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(env.Stderr, "Usage: zfsguard %s\n", lookup(name).usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args and reports whether the command should continue;
+// code is the exit code to return otherwise.
+func parseFlags(fs *flag.FlagSet, args []string) (ok bool, code int) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return false, ExitOK
+		}
+		return false, ExitUsage
+	}
+	return true, ExitOK
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeTSV writes one tab-separated line. Tabs and newlines inside fields
+// are replaced by spaces so every record stays on one line.
+func writeTSV(w io.Writer, fields ...string) {
+	for i, f := range fields {
+		fields[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(f)
+	}
+	fmt.Fprintln(w, strings.Join(fields, "\t"))
+}
+
+func listOrDash(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ",")
+}
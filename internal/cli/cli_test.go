@@ -0,0 +1,272 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/report"
+	"github.com/pbek/zfsguard/internal/zfs"
+	"github.com/pbek/zfsguard/internal/zfs/zfstest"
+)
+
+type result struct {
+	code           int
+	stdout, stderr string
+}
+
+func run(t *testing.T, cfg config.Config, r *zfstest.Runner, args ...string) result {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(&Env{Config: cfg, Runner: r, Stdout: &stdout, Stderr: &stderr}, args)
+	return result{code, stdout.String(), stderr.String()}
+}
+
+func scenario(t *testing.T, name string) *zfstest.Runner {
+	t.Helper()
+	r, err := zfstest.Scenario(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestList(t *testing.T) {
+	r := scenario(t, "healthy")
+	cfg := config.DefaultConfig()
+
+	res := run(t, cfg, r, "list", "tank/data")
+	want := "tank/data@zfsguard_2026-02-01\t1048576\t4294967296\t1769904000\t-\t0\n" +
+		"tank/data@zfsguard_2026-02-08\t52428800\t4345298944\t1770508800\t-\t1\n"
+	if res.code != ExitOK || res.stdout != want {
+		t.Errorf("list = %d %q, want %q", res.code, res.stdout, want)
+	}
+
+	res = run(t, cfg, r, "list", "--json", "-r", "tank")
+	var snaps []snapshotJSON
+	if err := json.Unmarshal([]byte(res.stdout), &snaps); err != nil {
+		t.Fatalf("invalid JSON %q: %v", res.stdout, err)
+	}
+	if len(snaps) != 3 || snaps[1].Clones[0] != "tank/home-restore" || snaps[0].Used != 1048576 {
+		t.Errorf("snapshots = %+v", snaps)
+	}
+}
+
+func TestCreateAndDestroy(t *testing.T) {
+	cfg := config.DefaultConfig()
+
+	res := run(t, cfg, scenario(t, "healthy"), "create", "tank/data@manual")
+	if res.code != ExitOK || res.stdout != "tank/data@manual\n" {
+		t.Errorf("create = %+v", res)
+	}
+	res = run(t, cfg, scenario(t, "permission-denied"), "create", "tank/data@manual")
+	if res.code != ExitFailure || !strings.Contains(res.stderr, "permission denied") {
+		t.Errorf("create without permission = %+v", res)
+	}
+
+	r := scenario(t, "healthy")
+	res = run(t, cfg, r, "destroy", "--dry-run", "tank/data@zfsguard_2026-02-01")
+	if res.code != ExitOK || !strings.HasSuffix(res.stdout, "reclaim\t1048576\n") {
+		t.Errorf("destroy --dry-run = %+v", res)
+	}
+	if r.Called("zfs destroy tank/data@zfsguard_2026-02-01") {
+		t.Error("dry run destroyed the snapshot")
+	}
+
+	res = run(t, cfg, r, "destroy", "--json", "tank/data@zfsguard_2026-02-01", "tank/data@missing")
+	var out destroyJSON
+	if err := json.Unmarshal([]byte(res.stdout), &out); err != nil {
+		t.Fatalf("invalid JSON %q: %v", res.stdout, err)
+	}
+	if res.code != ExitFailure || len(out.Destroyed) != 1 || out.Failed["tank/data@missing"] == "" {
+		t.Errorf("destroy = %d %+v", res.code, out)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Monitor.ReportPath = filepath.Join(t.TempDir(), "health-report.json")
+
+	res := run(t, cfg, zfstest.New(), "health")
+	if res.code != ExitUsage {
+		t.Errorf("health without report = %+v", res)
+	}
+
+	pools := []zfs.PoolStatus{{Name: "tank", State: "DEGRADED", Errors: "No known data errors"}}
+	r := report.FromChecks(pools, nil, nil, nil)
+	r.Timestamp = time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	if err := report.Write(cfg.Monitor.ReportPath, r); err != nil {
+		t.Fatal(err)
+	}
+	res = run(t, cfg, zfstest.New(), "health")
+	want := "checked\t2026-02-08T10:00:00Z\npool\ttank\tDEGRADED\tNo known data errors\n"
+	if res.code != ExitFailure || res.stdout != want {
+		t.Errorf("health = %d %q, want %q", res.code, res.stdout, want)
+	}
+
+	root := scenario(t, "healthy")
+	root.SetRoot(true)
+	res = run(t, cfg, root, "health", "--live", "--json")
+	var live report.HealthReport
+	if err := json.Unmarshal([]byte(res.stdout), &live); err != nil {
+		t.Fatalf("invalid JSON %q: %v", res.stdout, err)
+	}
+	if res.code != ExitOK || len(live.Pools) != 1 || len(live.Disks) != 2 {
+		t.Errorf("health --live = %d %+v", res.code, live)
+	}
+
+	// Without root, pools are still checked and SMART is unavailable.
+	user := scenario(t, "healthy")
+	res = run(t, cfg, user, "health", "--live", "--json")
+	live = report.HealthReport{}
+	if err := json.Unmarshal([]byte(res.stdout), &live); err != nil {
+		t.Fatalf("invalid JSON %q: %v", res.stdout, err)
+	}
+	if len(live.Pools) != 1 || len(live.Disks) != 0 || !strings.Contains(live.DiskError, "without root") {
+		t.Errorf("health --live without root = %+v", live)
+	}
+	if user.Called("smartctl --scan") {
+		t.Error("smartctl ran without root")
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Monitor.IntervalMinutes = 15
+	cfg.Monitor.ReportPath = filepath.Join(t.TempDir(), "health-report.json")
+	write := func(r report.HealthReport) {
+		t.Helper()
+		if err := report.Write(cfg.Monitor.ReportPath, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	online := []zfs.PoolStatus{{Name: "tank", State: "ONLINE"}}
+
+	r := report.FromChecks(online, nil, nil, nil)
+	write(r)
+	res := run(t, cfg, zfstest.New(), "health", "--check")
+	if res.code != ExitOK || !strings.HasPrefix(res.stdout, "OK: health report from ") {
+		t.Errorf("fresh healthy report = %+v", res)
+	}
+
+	// Two intervals of 15 minutes.
+	r.Timestamp = time.Now().Add(-31 * time.Minute)
+	write(r)
+	res = run(t, cfg, zfstest.New(), "health", "--check")
+	if res.code != ExitFailure || !strings.HasPrefix(res.stdout, "STALE: ") || !strings.Contains(res.stdout, "(limit 30m0s)") {
+		t.Errorf("stale report = %+v", res)
+	}
+	// Without --check only the health counts.
+	if res := run(t, cfg, zfstest.New(), "health"); res.code != ExitOK {
+		t.Errorf("stale report without --check = %+v", res)
+	}
+
+	write(report.FromChecks([]zfs.PoolStatus{{Name: "tank", State: "DEGRADED"}}, nil, nil, nil))
+	res = run(t, cfg, zfstest.New(), "health", "--check")
+	if res.code != ExitFailure || res.stdout != "UNHEALTHY: pool tank is DEGRADED\n" {
+		t.Errorf("unhealthy report = %+v", res)
+	}
+
+	if res := run(t, cfg, zfstest.New(), "health", "--check", "--json"); res.code != ExitUsage {
+		t.Errorf("--check --json = %+v", res)
+	}
+}
+
+func TestHealthHistory(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Monitor.ReportPath = filepath.Join(t.TempDir(), "health-report.json")
+	path := report.HistoryPath(cfg.Monitor.ReportPath)
+
+	res := run(t, cfg, zfstest.New(), "health", "history")
+	if res.code != ExitOK || res.stdout != "" {
+		t.Errorf("history without reports = %+v", res)
+	}
+
+	t0 := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	for i, state := range []string{"ONLINE", "ONLINE", "DEGRADED", "ONLINE"} {
+		pools := []zfs.PoolStatus{{Name: "tank", State: state}}
+		r := report.FromChecks(pools, nil, nil, nil)
+		r.Timestamp = t0.Add(time.Duration(i) * time.Hour)
+		if err := report.AppendHistory(path, r, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res = run(t, cfg, zfstest.New(), "health", "history")
+	want := "2026-02-08T10:00:00Z\tpool\ttank\t-\tONLINE\n" +
+		"2026-02-08T12:00:00Z\tpool\ttank\tONLINE\tDEGRADED\n" +
+		"2026-02-08T13:00:00Z\tpool\ttank\tDEGRADED\tONLINE\n"
+	if res.code != ExitOK || res.stdout != want {
+		t.Errorf("history = %d %q, want %q", res.code, res.stdout, want)
+	}
+
+	res = run(t, cfg, zfstest.New(), "health", "history", "--json", "--since", "2026-02-08T11:30:00Z")
+	var changes []report.StateChange
+	if err := json.Unmarshal([]byte(res.stdout), &changes); err != nil {
+		t.Fatalf("invalid JSON %q: %v", res.stdout, err)
+	}
+	// The first report in range sets the initial state.
+	if len(changes) != 2 || changes[0].To != "DEGRADED" || changes[0].From != "" || changes[1].To != "ONLINE" {
+		t.Errorf("history --since = %+v", changes)
+	}
+
+	if res := run(t, cfg, zfstest.New(), "health", "history", "--since", "yesterday"); res.code != ExitUsage {
+		t.Errorf("history --since yesterday = %+v", res)
+	}
+}
+
+func TestParseTimeArg(t *testing.T) {
+	now := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		arg  string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"24h", now.Add(-24 * time.Hour)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"2026-02-01T12:00:00Z", time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)},
+		{"2026-02-01", time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeArg(tt.arg, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseTimeArg(%q) = %v, %v; want %v", tt.arg, got, err, tt.want)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if res := run(t, cfg, zfstest.New(), "prune"); res.code != ExitUsage {
+		t.Errorf("prune without rules = %+v", res)
+	}
+
+	cfg.Retention.Datasets = []config.RetentionRule{{Dataset: "tank/data", Daily: 1}}
+	r := scenario(t, "healthy")
+	res := run(t, cfg, r, "prune", "--dry-run")
+	if res.code != ExitOK || res.stdout != "destroy\ttank/data@zfsguard_2026-02-01\nkept\t1\n" {
+		t.Errorf("prune --dry-run = %+v", res)
+	}
+	for _, c := range r.Calls() {
+		if strings.HasPrefix(c, "zfs destroy") {
+			t.Errorf("dry run ran %q", c)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if res := run(t, cfg, zfstest.New(), "frobnicate"); res.code != ExitUsage {
+		t.Errorf("unknown command = %+v", res)
+	}
+	if res := run(t, cfg, zfstest.New(), "list", "--bogus"); res.code != ExitUsage {
+		t.Errorf("unknown flag = %+v", res)
+	}
+	if res := run(t, cfg, zfstest.New(), "destroy", "tank/data"); res.code != ExitUsage {
+		t.Errorf("destroy of a dataset = %+v", res)
+	}
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/monitor"
+	"github.com/pbek/zfsguard/internal/report"
+)
+
+func runHealth(env *Env, args []string) int {
+	if len(args) > 0 && args[0] == "history" {
+		return runHealthHistory(env, args[1:])
+	}
+	fs := newFlags(env, "health")
+	asJSON := fs.Bool("json", false, "print the health report as JSON")
+	live := fs.Bool("live", false, "run the checks now instead of reading the monitor's report (needs root for SMART)")
+	check := fs.Bool("check", false, "print one status line and also fail when the report is stale, for external checks")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *check && *asJSON {
+		fmt.Fprintln(env.Stderr, "zfsguard: --check and --json cannot be combined")
+		return ExitUsage
+	}
+
+	var r report.HealthReport
+	if *live {
+		r = monitor.New(env.Config, env.Runner).LiveReport()
+	} else {
+		path := env.Config.Monitor.ReportPath
+		if path == "" {
+			path = report.DefaultPath
+		}
+		var err error
+		if r, err = report.Read(path); err != nil {
+			fmt.Fprintf(env.Stderr, "zfsguard: %v\n", err)
+			return ExitUsage
+		}
+	}
+
+	if *check {
+		return checkHealth(env, r, !*live)
+	}
+	if *asJSON {
+		if err := writeJSON(env.Stdout, r); err != nil {
+			fmt.Fprintf(env.Stderr, "zfsguard: %v\n", err)
+			return ExitUsage
+		}
+	} else {
+		writeHealthTSV(env, r)
+	}
+
+	if !r.Healthy() {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// checkHealth prints whether the report is OK, STALE or UNHEALTHY in one
+// line, e.g. for a Nagios-style check. A report read from disk is stale
+// when it is older than two monitor intervals (report.StaleAfter).
+func checkHealth(env *Env, r report.HealthReport, fromFile bool) int {
+	checked := r.Timestamp.Format(time.RFC3339)
+	age := time.Since(r.Timestamp).Round(time.Second)
+	maxAge := report.StaleAfter(env.Config.Monitor.IntervalMinutes)
+	switch {
+	case fromFile && r.Stale(time.Now(), maxAge):
+		fmt.Fprintf(env.Stdout, "STALE: health report from %s is %s old (limit %s); is zfsguard-monitor running?\n",
+			checked, age, maxAge)
+		return ExitFailure
+	case !r.Healthy():
+		fmt.Fprintf(env.Stdout, "UNHEALTHY: %s\n", strings.Join(r.Problems(), "; "))
+		return ExitFailure
+	}
+	fmt.Fprintf(env.Stdout, "OK: health report from %s (%s old)\n", checked, age)
+	return ExitOK
+}
+
+func writeHealthTSV(env *Env, r report.HealthReport) {
+	writeTSV(env.Stdout, "checked", r.Timestamp.Format(time.RFC3339))
+	if r.PoolError != "" {
+		writeTSV(env.Stdout, "error", "pools", r.PoolError)
+	}
+	for _, p := range r.Pools {
+		errors := p.Errors
+		if errors == "" {
+			errors = "-"
+		}
+		writeTSV(env.Stdout, "pool", p.Name, p.State, errors)
+	}
+	if r.DiskError != "" {
+		writeTSV(env.Stdout, "error", "disks", r.DiskError)
+	}
+	for _, d := range r.Disks {
+		summary := d.Summary
+		for _, issue := range d.Issues {
+			summary += "; " + issue
+		}
+		writeTSV(env.Stdout, "disk", d.Device, string(d.Level()), summary)
+	}
+}
+
+// runHealthHistory prints when each pool's state and each disk's health
+// changed, from the monitor's report history.
+func runHealthHistory(env *Env, args []string) int {
+	fs := newFlags(env, "health history")
+	asJSON := fs.Bool("json", false, "print the changes as JSON")
+	sinceArg := fs.String("since", "", "only reports since a time: a duration (24h, 7d) ago, a date (2006-01-02) or RFC 3339")
+	untilArg := fs.String("until", "", "only reports until a time, in the same formats as --since")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	now := time.Now()
+	since, err := parseTimeArg(*sinceArg, now)
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "zfsguard: invalid --since: %v\n", err)
+		return ExitUsage
+	}
+	until, err := parseTimeArg(*untilArg, now)
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "zfsguard: invalid --until: %v\n", err)
+		return ExitUsage
+	}
+
+	path := env.Config.Monitor.ReportPath
+	if path == "" {
+		path = report.DefaultPath
+	}
+	reports, err := report.History(report.HistoryPath(path), since, until)
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "zfsguard: %v\n", err)
+		return ExitUsage
+	}
+	changes := report.Changes(reports)
+
+	if *asJSON {
+		if changes == nil {
+			changes = []report.StateChange{}
+		}
+		if err := writeJSON(env.Stdout, changes); err != nil {
+			fmt.Fprintf(env.Stderr, "zfsguard: %v\n", err)
+			return ExitUsage
+		}
+		return ExitOK
+	}
+	for _, c := range changes {
+		from := c.From
+		if from == "" {
+			from = "-"
+		}
+		writeTSV(env.Stdout, c.Time.Format(time.RFC3339), c.Kind, c.Name, from, c.To)
+	}
+	return ExitOK
+}
+
+// parseTimeArg parses a --since or --until value: a duration before now
+// ("24h", or "7d" for days), a date or an RFC 3339 time. Empty is the zero
+// time.
+func parseTimeArg(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration, date or RFC 3339 time", s)
+}
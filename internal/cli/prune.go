@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pbek/zfsguard/internal/monitor"
+)
+
+func runPrune(env *Env, args []string) int {
+	fs := newFlags(env, "prune")
+	dryRun := fs.Bool("dry-run", false, "only print what would be pruned")
+	asJSON := fs.Bool("json", false, "print JSON instead of tab-separated values")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if len(env.Config.Retention.Datasets) == 0 {
+		fmt.Fprintln(env.Stderr, "zfsguard: no retention rules configured (retention.datasets)")
+		return ExitUsage
+	}
+
+	pr, err := monitor.New(env.Config, env.Runner).Prune(*dryRun || env.Config.Retention.DryRun)
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "zfsguard: %v\n", err)
+		return ExitFailure
+	}
+
+	if *asJSON {
+		if err := writeJSON(env.Stdout, pr); err != nil {
+			fmt.Fprintf(env.Stderr, "zfsguard: %v\n", err)
+			return ExitFailure
+		}
+	} else {
+		action := "destroyed"
+		if pr.DryRun {
+			action = "destroy"
+		}
+		for _, name := range pr.Destroyed {
+			writeTSV(env.Stdout, action, name)
+		}
+		for _, f := range pr.Failed {
+			writeTSV(env.Stdout, "failed", f.Snapshot, f.Error)
+		}
+		writeTSV(env.Stdout, "kept", strconv.Itoa(pr.Kept))
+	}
+
+	if len(pr.Failed) > 0 {
+		return ExitFailure
+	}
+	return ExitOK
+}
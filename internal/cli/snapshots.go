@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/zfs"
+)
+
+// snapshotJSON is the JSON form of a snapshot in "zfsguard list --json".
+type snapshotJSON struct {
+	Name     string    `json:"name"`
+	Dataset  string    `json:"dataset"`
+	Snapshot string    `json:"snapshot"`
+	Used     uint64    `json:"used"`
+	Refer    uint64    `json:"refer"`
+	Creation time.Time `json:"creation"`
+	Clones   []string  `json:"clones"`
+	UserRefs int       `json:"userrefs"`
+}
+
+func runList(env *Env, args []string) int {
+	fs := newFlags(env, "list")
+	asJSON := fs.Bool("json", false, "print JSON instead of tab-separated values")
+	recursive := fs.Bool("r", false, "include snapshots of descendant datasets")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	snaps, err := zfs.NewClient(env.Runner).ListSnapshots()
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "zfsguard: %v\n", err)
+		return ExitFailure
+	}
+	snaps = filterSnapshots(snaps, fs.Args(), *recursive)
+
+	if *asJSON {
+		out := make([]snapshotJSON, 0, len(snaps))
+		for _, s := range snaps {
+			clones := s.Clones
+			if clones == nil {
+				clones = []string{}
+			}
+			out = append(out, snapshotJSON{
+				Name:     s.Name,
+				Dataset:  s.Dataset,
+				Snapshot: s.ShortName,
+				Used:     s.UsedBytes,
+				Refer:    s.ReferBytes,
+				Creation: s.Creation,
+				Clones:   clones,
+				UserRefs: s.UserRefs,
+			})
+		}
+		if err := writeJSON(env.Stdout, out); err != nil {
+			fmt.Fprintf(env.Stderr, "zfsguard: %v\n", err)
+			return ExitFailure
+		}
+		return ExitOK
+	}
+
+	// Same columns and units as "zfs list -Hp": bytes and Unix timestamps.
+	for _, s := range snaps {
+		writeTSV(env.Stdout,
+			s.Name,
+			strconv.FormatUint(s.UsedBytes, 10),
+			strconv.FormatUint(s.ReferBytes, 10),
+			strconv.FormatInt(s.Creation.Unix(), 10),
+			listOrDash(s.Clones),
+			strconv.Itoa(s.UserRefs),
+		)
+	}
+	return ExitOK
+}
+
+// filterSnapshots keeps the snapshots of the given datasets (and, with
+// recursive, their descendants). No datasets keeps everything.
+func filterSnapshots(snaps []zfs.Snapshot, datasets []string, recursive bool) []zfs.Snapshot {
+	if len(datasets) == 0 {
+		return snaps
+	}
+	var out []zfs.Snapshot
+	for _, s := range snaps {
+		for _, d := range datasets {
+			if s.Dataset == d || (recursive && strings.HasPrefix(s.Dataset, d+"/")) {
+				out = append(out, s)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func runCreate(env *Env, args []string) int {
+	fs := newFlags(env, "create")
+	recursive := fs.Bool("r", false, "also snapshot all descendant datasets")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return ExitUsage
+	}
+
+	z := zfs.NewClient(env.Runner)
+	code := ExitOK
+	for _, name := range fs.Args() {
+		// A bare dataset gets the name the TUI would suggest.
+		if !strings.Contains(name, "@") {
+			name = fmt.Sprintf("%s@%s_%s",
+				name, env.Config.Defaults.SnapshotPrefix, time.Now().Format("2006-01-02_15-04-05"))
+		}
+		if err := z.CreateSnapshot(name, *recursive); err != nil {
+			fmt.Fprintf(env.Stderr, "zfsguard: %v\n", strings.TrimSpace(err.Error()))
+			code = ExitFailure
+			continue
+		}
+		fmt.Fprintln(env.Stdout, name)
+	}
+	return code
+}
+
+// destroyJSON is the JSON output of "zfsguard destroy".
+type destroyJSON struct {
+	DryRun      bool              `json:"dry_run,omitempty"`
+	Destroyed   []string          `json:"destroyed"`
+	Failed      map[string]string `json:"failed,omitempty"`
+	Reclaimable *uint64           `json:"reclaimable,omitempty"`
+}
+
+func runDestroy(env *Env, args []string) int {
+	fs := newFlags(env, "destroy")
+	dryRun := fs.Bool("dry-run", false, "only print what would be destroyed and the reclaimable space")
+	asJSON := fs.Bool("json", false, "print JSON instead of tab-separated values")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	names := fs.Args()
+	if len(names) == 0 {
+		fs.Usage()
+		return ExitUsage
+	}
+	for _, n := range names {
+		if !strings.Contains(n, "@") {
+			fmt.Fprintf(env.Stderr, "zfsguard: %q is not a snapshot name (dataset@snapshot)\n", n)
+			return ExitUsage
+		}
+	}
+
+	z := zfs.NewClient(env.Runner)
+	if *dryRun {
+		reclaim, err := z.EstimateDestroy(names)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "zfsguard: %v\n", strings.TrimSpace(err.Error()))
+			return ExitFailure
+		}
+		if *asJSON {
+			out := destroyJSON{DryRun: true, Destroyed: names, Reclaimable: &reclaim}
+			if err := writeJSON(env.Stdout, out); err != nil {
+				fmt.Fprintf(env.Stderr, "zfsguard: %v\n", err)
+				return ExitFailure
+			}
+			return ExitOK
+		}
+		for _, n := range names {
+			writeTSV(env.Stdout, "destroy", n)
+		}
+		writeTSV(env.Stdout, "reclaim", strconv.FormatUint(reclaim, 10))
+		return ExitOK
+	}
+
+	results := z.DestroySnapshots(names)
+	out := destroyJSON{Destroyed: []string{}, Failed: map[string]string{}}
+	for _, n := range names {
+		if err := results[n]; err != nil {
+			out.Failed[n] = strings.TrimSpace(err.Error())
+			continue
+		}
+		out.Destroyed = append(out.Destroyed, n)
+	}
+
+	if *asJSON {
+		if err := writeJSON(env.Stdout, out); err != nil {
+			fmt.Fprintf(env.Stderr, "zfsguard: %v\n", err)
+			return ExitFailure
+		}
+	} else {
+		for _, n := range out.Destroyed {
+			writeTSV(env.Stdout, "destroyed", n)
+		}
+		for _, n := range names {
+			if msg, ok := out.Failed[n]; ok {
+				fmt.Fprintf(env.Stderr, "zfsguard: %s\n", msg)
+			}
+		}
+	}
+	if len(out.Failed) > 0 {
+		return ExitFailure
+	}
+	return ExitOK
+}
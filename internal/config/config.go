@@ -11,9 +11,12 @@ import (
 
 // Config is the root configuration for zfsguard.
 type Config struct {
-	Monitor  MonitorConfig  `yaml:"monitor"`
-	Notify   NotifyConfig   `yaml:"notify"`
-	Defaults DefaultsConfig `yaml:"defaults"`
+	Monitor   MonitorConfig   `yaml:"monitor"`
+	Notify    NotifyConfig    `yaml:"notify"`
+	Defaults  DefaultsConfig  `yaml:"defaults"`
+	Retention RetentionConfig `yaml:"retention"`
+	Snapshots SnapshotsConfig `yaml:"snapshots"`
+	Scrubs    ScrubsConfig    `yaml:"scrubs"`
 }
 
 // MonitorConfig holds settings for the monitoring service.
@@ -23,6 +26,60 @@ type MonitorConfig struct {
 	CheckSMART      bool     `yaml:"check_smart"`
 	SMARTDevices    []string `yaml:"smart_devices"`
 	ReportPath      string   `yaml:"report_path"`
+	// HistoryDays is how many days of reports are kept in
+	// health-history.jsonl next to the report; 0 disables the history.
+	HistoryDays int `yaml:"history_days"`
+	// SMARTRules grade disks that pass the SMART self-assessment but whose
+	// attributes are above a threshold.
+	SMARTRules []SMARTRule `yaml:"smart_rules"`
+	// CapacityWarning and CapacityCritical are the pool capacity, in
+	// percent allocated, at which a pool is reported as warning or
+	// critical; 0 disables a threshold.
+	CapacityWarning  int `yaml:"capacity_warning"`
+	CapacityCritical int `yaml:"capacity_critical"`
+	// ScrubMaxAgeDays is how many days may pass since a pool's last
+	// finished scrub before it is reported as a warning; 0 disables it.
+	ScrubMaxAgeDays int `yaml:"scrub_max_age_days"`
+	// MetricsAddress is the address, e.g. ":9732", on which the monitor
+	// serves Prometheus metrics at /metrics. Empty disables the listener.
+	MetricsAddress string `yaml:"metrics_address"`
+	// TextfilePath is a .prom file the monitor writes the same metrics to
+	// after each check cycle, for node_exporter's textfile collector.
+	// Empty disables it.
+	TextfilePath string `yaml:"textfile_path"`
+	// APIAddress is the address on which the monitor serves the health
+	// report as JSON (/health, /report, /pools/{name}, /disks/{device}):
+	// a TCP address such as "127.0.0.1:9733" or "unix:/run/zfsguard/api.sock".
+	// Empty disables the API.
+	APIAddress string `yaml:"api_address"`
+	// Heartbeat is pinged after every check cycle, so that an external
+	// service alerts when the pings stop because the host or the monitor
+	// died.
+	Heartbeat HeartbeatConfig `yaml:"heartbeat"`
+}
+
+// HeartbeatConfig sets where the monitor reports that it is alive. Either
+// or both URLs may be set; empty disables them.
+type HeartbeatConfig struct {
+	// URL receives an HTTP GET after every check cycle, healthchecks.io
+	// style: "<url>/fail" when the cycle found issues or could not send its
+	// notifications, "<url>" otherwise.
+	URL string `yaml:"url"`
+	// ShoutrrrURL receives a short status message after every check cycle,
+	// for dead man's switch services reached through Shoutrrr.
+	ShoutrrrURL string `yaml:"shoutrrr_url"`
+}
+
+// SMARTRule raises a disk to warning or critical when a SMART statistic is
+// above a threshold. Attribute is an ATA attribute name (compared by raw
+// value, e.g. "Reallocated_Sector_Ct"), "temperature" (°C),
+// "power_on_hours", an NVMe health log field such as "percentage_used" or
+// "media_errors", or a SCSI counter such as "grown_defects". Disks that do
+// not report the attribute are skipped. Either threshold may be omitted.
+type SMARTRule struct {
+	Attribute string   `yaml:"attribute"`
+	Warning   *float64 `yaml:"warning"`
+	Critical  *float64 `yaml:"critical"`
 }
 
 // NotifyConfig holds notification service settings.
@@ -37,8 +94,29 @@ type NotifyConfig struct {
 	//   - "ntfy://ntfy.sh/topic"
 	ShoutrrrURLs []string `yaml:"shoutrrr_urls"`
 
+	// Routes send notifications to specific URLs by severity and, optionally,
+	// pool or device. Every matching route receives the notification;
+	// ShoutrrrURLs only receive notifications no route matches.
+	Routes []NotifyRoute `yaml:"routes"`
+
 	// Desktop enables local Linux desktop notifications via notify-send / D-Bus.
 	Desktop bool `yaml:"desktop"`
+
+	// RenotifyInterval is how often an issue that is still open is notified
+	// again, as a Go duration such as "24h". Empty or "0" never re-notifies;
+	// new issues, escalations and recoveries are always notified.
+	RenotifyInterval string `yaml:"renotify_interval"`
+}
+
+// NotifyRoute sends the notifications it matches to URLs. Empty fields match
+// everything; Pools and Devices are glob patterns such as "tank*" or
+// "/dev/nvme*".
+type NotifyRoute struct {
+	// Severities are "info", "warning" and "critical".
+	Severities []string `yaml:"severities"`
+	Pools      []string `yaml:"pools"`
+	Devices    []string `yaml:"devices"`
+	URLs       []string `yaml:"urls"`
 }
 
 // DefaultsConfig holds default values for snapshot operations.
@@ -46,17 +124,85 @@ type DefaultsConfig struct {
 	SnapshotPrefix string `yaml:"snapshot_prefix"`
 }
 
+// RetentionConfig holds the snapshot retention rules applied by the
+// monitor's pruning pass. Without rules nothing is pruned.
+type RetentionConfig struct {
+	// DryRun logs and reports the pruning decisions without destroying
+	// any snapshot.
+	DryRun   bool            `yaml:"dry_run"`
+	Datasets []RetentionRule `yaml:"datasets"`
+}
+
+// RetentionRule says how many snapshots of a dataset to keep per period.
+// Only snapshots whose name starts with "<snapshot_prefix>_" are considered;
+// the newest snapshot of each of the last N hours, days, ISO weeks, months
+// and years is kept and every other one is pruned.
+type RetentionRule struct {
+	Dataset string `yaml:"dataset"`
+	// Recursive applies the rule to every descendant dataset as well, unless
+	// a more specific rule exists for it.
+	Recursive bool `yaml:"recursive"`
+
+	Hourly  int `yaml:"hourly"`
+	Daily   int `yaml:"daily"`
+	Weekly  int `yaml:"weekly"`
+	Monthly int `yaml:"monthly"`
+	Yearly  int `yaml:"yearly"`
+}
+
+// SnapshotsConfig holds the schedules on which the monitor takes snapshots.
+type SnapshotsConfig struct {
+	Schedules []SnapshotSchedule `yaml:"schedules"`
+}
+
+// SnapshotSchedule takes a snapshot of a dataset named
+// "<snapshot_prefix>_<label>_<timestamp>" either at a fixed interval or
+// whenever a cron expression matches. Exactly one of Interval and Cron must
+// be set.
+type SnapshotSchedule struct {
+	Dataset string `yaml:"dataset"`
+	// Label distinguishes the schedules of a dataset, e.g. "hourly".
+	Label string `yaml:"label"`
+	// Recursive also snapshots all descendant datasets (zfs snapshot -r).
+	Recursive bool `yaml:"recursive"`
+	// Interval is a Go duration such as "15m" or "1h", aligned to the clock.
+	Interval string `yaml:"interval"`
+	// Cron is a five-field cron expression such as "0 */6 * * *".
+	Cron string `yaml:"cron"`
+}
+
+// ScrubsConfig holds the schedules on which the monitor starts scrubs.
+type ScrubsConfig struct {
+	Schedules []ScrubSchedule `yaml:"schedules"`
+}
+
+// ScrubSchedule starts a scrub of a pool either at a fixed interval or
+// whenever a cron expression matches. Exactly one of Interval and Cron must
+// be set. A pool that is already scrubbing or resilvering is skipped.
+type ScrubSchedule struct {
+	Pool string `yaml:"pool"`
+	// Interval is a Go duration such as "168h", aligned to the clock.
+	Interval string `yaml:"interval"`
+	// Cron is a five-field cron expression such as "0 2 1 * *".
+	Cron string `yaml:"cron"`
+}
+
 // DefaultConfig returns a config with sane defaults.
 func DefaultConfig() Config {
 	return Config{
 		Monitor: MonitorConfig{
-			IntervalMinutes: 60,
-			CheckZFS:        true,
-			CheckSMART:      true,
-			ReportPath:      "/var/lib/zfsguard/health-report.json",
+			IntervalMinutes:  60,
+			CheckZFS:         true,
+			CheckSMART:       true,
+			ReportPath:       "/var/lib/zfsguard/health-report.json",
+			HistoryDays:      30,
+			SMARTRules:       DefaultSMARTRules(),
+			CapacityWarning:  80,
+			CapacityCritical: 90,
 		},
 		Notify: NotifyConfig{
-			Desktop: true,
+			Desktop:          true,
+			RenotifyInterval: "24h",
 		},
 		Defaults: DefaultsConfig{
 			SnapshotPrefix: "zfsguard",
@@ -64,6 +210,21 @@ func DefaultConfig() Config {
 	}
 }
 
+// DefaultSMARTRules returns the threshold rules used when the config does
+// not set monitor.smart_rules.
+func DefaultSMARTRules() []SMARTRule {
+	threshold := func(v float64) *float64 { return &v }
+	return []SMARTRule{
+		{Attribute: "Reallocated_Sector_Ct", Warning: threshold(0)},
+		{Attribute: "Current_Pending_Sector", Warning: threshold(0), Critical: threshold(10)},
+		{Attribute: "Offline_Uncorrectable", Warning: threshold(0)},
+		{Attribute: "percentage_used", Warning: threshold(80)},
+		{Attribute: "media_errors", Warning: threshold(0)},
+		{Attribute: "grown_defects", Warning: threshold(0)},
+		{Attribute: "temperature", Warning: threshold(55), Critical: threshold(65)},
+	}
+}
+
 // Load reads the config from the given path. If the file does not exist,
 // it returns the default configuration.
 func Load(path string) (Config, error) {
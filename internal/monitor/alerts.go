@@ -0,0 +1,277 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/notify"
+)
+
+// alertStateFile is the name of the alert state file, stored in the
+// directory of the health report.
+const alertStateFile = "alert-state.json"
+
+// issue is a problem found by a check cycle. Key identifies it across
+// cycles, e.g. "pool:tank:state" or "smart:/dev/sdb", so a pool that stays
+// DEGRADED is the same issue even when its message changes. Pool and Device
+// are used for notification routing.
+type issue struct {
+	Key      string
+	Severity notify.Severity
+	Message  string
+	Pool     string
+	Device   string
+}
+
+// openIssue is an issue that has been reported and not resolved yet.
+type openIssue struct {
+	// Key is the key of the issue, set for resolved issues only.
+	Key          string          `json:"-"`
+	Severity     notify.Severity `json:"severity"`
+	Message      string          `json:"message"`
+	Pool         string          `json:"pool,omitempty"`
+	Device       string          `json:"device,omitempty"`
+	Since        time.Time       `json:"since"`
+	LastNotified time.Time       `json:"last_notified"`
+}
+
+// alertState tracks the open issues across check cycles, so that an issue
+// is only notified when it appears, escalates, recovers or is due for a
+// reminder.
+type alertState struct {
+	Open map[string]openIssue `json:"open"`
+}
+
+// alertUpdate is what changed since the previous cycle.
+type alertUpdate struct {
+	New       []issue
+	Escalated []issue
+	Reminders []issue
+	Resolved  []openIssue
+}
+
+func (u alertUpdate) empty() bool {
+	return !u.alerting() && len(u.Resolved) == 0
+}
+
+// alerting reports whether the update has open issues to notify.
+func (u alertUpdate) alerting() bool {
+	return len(u.New)+len(u.Escalated)+len(u.Reminders) > 0
+}
+
+// split divides the update by the routes its issues match, so that every
+// part can be sent as its own notification. route identifies the routes of a
+// message, see notify.Notifier.RouteKey; every issue is matched on its own
+// severity, pool and device.
+func (u alertUpdate) split(route func(notify.Message) string) []alertUpdate {
+	var parts []alertUpdate
+	index := map[string]int{}
+	part := func(severity notify.Severity, pool, device string) *alertUpdate {
+		key := route(notify.Message{
+			Severity: severity,
+			Pools:    appendUnique(nil, pool),
+			Devices:  appendUnique(nil, device),
+		})
+		i, ok := index[key]
+		if !ok {
+			i = len(parts)
+			index[key] = i
+			parts = append(parts, alertUpdate{})
+		}
+		return &parts[i]
+	}
+	for _, i := range u.New {
+		p := part(i.Severity, i.Pool, i.Device)
+		p.New = append(p.New, i)
+	}
+	for _, i := range u.Escalated {
+		p := part(i.Severity, i.Pool, i.Device)
+		p.Escalated = append(p.Escalated, i)
+	}
+	for _, i := range u.Reminders {
+		p := part(i.Severity, i.Pool, i.Device)
+		p.Reminders = append(p.Reminders, i)
+	}
+	for _, o := range u.Resolved {
+		p := part(notify.Info, o.Pool, o.Device)
+		p.Resolved = append(p.Resolved, o)
+	}
+	return parts
+}
+
+// alert returns the notification for the open issues of the update. Its
+// severity is the highest of the issues.
+func (u alertUpdate) alert() notify.Message {
+	m := notify.Message{Title: "ZFSGuard Alert", Body: u.message(), Severity: notify.Info}
+	for _, list := range [][]issue{u.New, u.Escalated, u.Reminders} {
+		for _, i := range list {
+			if i.Severity.Rank() > m.Severity.Rank() {
+				m.Severity = i.Severity
+			}
+			m.Pools = appendUnique(m.Pools, i.Pool)
+			m.Devices = appendUnique(m.Devices, i.Device)
+		}
+	}
+	return m
+}
+
+// resolved returns the info notification for the resolved issues.
+func (u alertUpdate) resolved(now time.Time) notify.Message {
+	m := notify.Message{Title: "ZFSGuard Resolved", Body: u.resolvedMessage(now), Severity: notify.Info}
+	for _, o := range u.Resolved {
+		m.Pools = appendUnique(m.Pools, o.Pool)
+		m.Devices = appendUnique(m.Devices, o.Device)
+	}
+	return m
+}
+
+func appendUnique(list []string, s string) []string {
+	if s == "" || slices.Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
+
+// message renders the open issues of the update as the body of an alert.
+func (u alertUpdate) message() string {
+	var b strings.Builder
+	section := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(title + ":\n")
+		for _, l := range lines {
+			b.WriteString("- " + l + "\n")
+		}
+	}
+	messages := func(issues []issue) []string {
+		var out []string
+		for _, i := range issues {
+			out = append(out, i.Message)
+		}
+		return out
+	}
+	section("New issues", messages(u.New))
+	section("Escalated", messages(u.Escalated))
+	section("Still open", messages(u.Reminders))
+	return b.String()
+}
+
+// resolvedMessage renders the resolved issues with how long each lasted,
+// e.g. "- ZFS: Pool "tank" is in state: DEGRADED (lasted 3h 12m)".
+func (u alertUpdate) resolvedMessage(now time.Time) string {
+	var b strings.Builder
+	for _, o := range u.Resolved {
+		fmt.Fprintf(&b, "- %s (lasted %s, since %s)\n",
+			o.Message, formatDuration(now.Sub(o.Since)), o.Since.Local().Format("2006-01-02 15:04"))
+	}
+	return b.String()
+}
+
+// formatDuration renders d rounded to minutes, e.g. "2d 3h", "3h 12m" or
+// "45m".
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return "less than a minute"
+}
+
+// update compares the issues of this cycle with the open ones and returns
+// the changes together with the state to keep if they are notified. Open
+// issues are reminded of every renotify; zero disables reminders.
+func (st alertState) update(now time.Time, issues []issue, renotify time.Duration) (alertUpdate, alertState) {
+	var u alertUpdate
+	next := alertState{Open: make(map[string]openIssue, len(issues))}
+
+	for _, i := range issues {
+		prev, ok := st.Open[i.Key]
+		switch {
+		case !ok:
+			u.New = append(u.New, i)
+			prev = openIssue{Since: now, LastNotified: now}
+		case i.Severity.Rank() > prev.Severity.Rank():
+			u.Escalated = append(u.Escalated, i)
+			prev.LastNotified = now
+		case renotify > 0 && now.Sub(prev.LastNotified) >= renotify:
+			u.Reminders = append(u.Reminders, i)
+			prev.LastNotified = now
+		}
+		prev.Severity = i.Severity
+		prev.Message = i.Message
+		prev.Pool = i.Pool
+		prev.Device = i.Device
+		next.Open[i.Key] = prev
+	}
+
+	var keys []string
+	for key := range st.Open {
+		if _, ok := next.Open[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		o := st.Open[key]
+		o.Key = key
+		u.Resolved = append(u.Resolved, o)
+	}
+	return u, next
+}
+
+// loadAlertState reads the alert state; a missing file is an empty state.
+func loadAlertState(path string) (alertState, error) {
+	st := alertState{Open: map[string]openIssue{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, fmt.Errorf("failed to read alert state: %w", err)
+	}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return alertState{Open: map[string]openIssue{}}, fmt.Errorf("failed to parse alert state: %w", err)
+	}
+	if st.Open == nil {
+		st.Open = map[string]openIssue{}
+	}
+	return st, nil
+}
+
+// saveAlertState atomically writes the alert state.
+func saveAlertState(path string, st alertState) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create alert state directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write alert state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to rename alert state: %w", err)
+	}
+	return nil
+}
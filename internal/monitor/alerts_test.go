@@ -0,0 +1,284 @@
+package monitor
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/notify"
+	"github.com/pbek/zfsguard/internal/zfs/zfstest"
+)
+
+// recorder is a sender that records every notification. While fail is set
+// every Send fails. routes, if set, provides the routing.
+type recorder struct {
+	messages []string
+	sent     []notify.Message
+	fail     bool
+	routes   *notify.Notifier
+}
+
+func (r *recorder) Notify(m notify.Message) error {
+	if r.fail {
+		return errors.New("connection refused")
+	}
+	r.messages = append(r.messages, m.Title+"\n"+m.Body)
+	r.sent = append(r.sent, m)
+	return nil
+}
+
+func (r *recorder) RouteKey(m notify.Message) string {
+	if r.routes == nil {
+		return ""
+	}
+	return r.routes.RouteKey(m)
+}
+
+func TestAlertStateUpdate(t *testing.T) {
+	t0 := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	degraded := issue{Key: "pool:tank:state", Severity: notify.Warning, Pool: "tank",
+		Message: `ZFS: Pool "tank" is in state: DEGRADED`}
+	faulted := issue{Key: "pool:tank:state", Severity: notify.Critical, Pool: "tank",
+		Message: `ZFS: Pool "tank" is in state: FAULTED`}
+	disk := issue{Key: "smart:/dev/sdb", Severity: notify.Warning, Device: "/dev/sdb",
+		Message: "SMART: Device /dev/sdb: PASSED"}
+
+	st := alertState{Open: map[string]openIssue{}}
+	u, st := st.update(t0, []issue{degraded}, 24*time.Hour)
+	if len(u.New) != 1 || u.New[0] != degraded {
+		t.Fatalf("first cycle = %+v, want the new issue", u)
+	}
+
+	u, st = st.update(t0.Add(time.Hour), []issue{degraded}, 24*time.Hour)
+	if !u.empty() {
+		t.Fatalf("unchanged issue notified again: %+v", u)
+	}
+
+	u, st = st.update(t0.Add(2*time.Hour), []issue{faulted, disk}, 24*time.Hour)
+	if len(u.Escalated) != 1 || u.Escalated[0] != faulted || len(u.New) != 1 || u.New[0] != disk {
+		t.Fatalf("escalation = %+v", u)
+	}
+
+	u, st = st.update(t0.Add(25*time.Hour), []issue{faulted, disk}, 24*time.Hour)
+	if !u.empty() {
+		t.Fatalf("reminder before the interval: %+v", u)
+	}
+	u, st = st.update(t0.Add(26*time.Hour), []issue{faulted, disk}, 24*time.Hour)
+	if len(u.Reminders) != 2 {
+		t.Fatalf("reminders = %+v, want both issues", u)
+	}
+
+	u, st = st.update(t0.Add(27*time.Hour), []issue{faulted}, 24*time.Hour)
+	if len(u.Resolved) != 1 || u.Resolved[0].Message != disk.Message {
+		t.Fatalf("recovery = %+v", u)
+	}
+	if _, ok := st.Open[disk.Key]; ok {
+		t.Error("resolved issue still open")
+	}
+
+	u, _ = st.update(t0.Add(100*time.Hour), []issue{faulted}, 0)
+	if !u.empty() {
+		t.Errorf("reminder sent with re-notify disabled: %+v", u)
+	}
+}
+
+func TestRunOnceNotifiesChangesOnly(t *testing.T) {
+	svc, path := newTestService(t, "degraded")
+	rec := &recorder{}
+	svc.notifier = rec
+	now := time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	for range 3 {
+		if err := svc.RunOnce(); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		now = now.Add(time.Hour)
+	}
+	if len(rec.messages) != 1 || !strings.Contains(rec.messages[0], "New issues:") {
+		t.Fatalf("notifications = %q, want one new-issue alert", rec.messages)
+	}
+
+	// A restarted monitor keeps the state, so it stays silent until the
+	// re-notify interval is over.
+	r, err := zfstest.Scenario("degraded")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted := New(svc.cfg, r)
+	restarted.notifier = rec
+	restarted.now = func() time.Time { return now }
+	if err := restarted.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if len(rec.messages) != 1 {
+		t.Fatalf("restarted monitor notified again: %q", rec.messages[1:])
+	}
+	now = now.Add(24 * time.Hour)
+	if err := restarted.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if len(rec.messages) != 2 || !strings.Contains(rec.messages[1], "Still open:") {
+		t.Fatalf("notifications = %q, want a reminder", rec.messages)
+	}
+
+	// The pool recovers.
+	r, err = zfstest.Scenario("healthy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovered := New(svc.cfg, r)
+	recovered.notifier = rec
+	recovered.now = func() time.Time { return now }
+	if err := recovered.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	// Opened at 10:00, three hourly cycles, then 24 hours later.
+	want := "ZFSGuard Resolved\n" +
+		"- ZFS: Pool \"tank\" device sdb FAULTED (read 3, write 120, cksum 0: too many errors) (lasted 1d 3h, since " +
+		time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC).Local().Format("2006-01-02 15:04") + ")\n"
+	if len(rec.messages) != 3 || !strings.HasPrefix(rec.messages[2], want) ||
+		!strings.Contains(rec.messages[2], `Pool "tank" is in state: DEGRADED (lasted 1d 3h`) {
+		t.Fatalf("notifications = %q, want a recovery starting with %q", rec.messages, want)
+	}
+	st, err := loadAlertState(filepath.Join(filepath.Dir(path), alertStateFile))
+	if err != nil || len(st.Open) != 0 {
+		t.Errorf("alert state after recovery = %+v, %v", st, err)
+	}
+}
+
+func TestRunOnceRetriesFailedNotifications(t *testing.T) {
+	svc, _ := newTestService(t, "degraded")
+	rec := &recorder{fail: true}
+	svc.notifier = rec
+
+	if err := svc.RunOnce(); err == nil {
+		t.Fatal("RunOnce succeeded although the notification failed")
+	}
+	rec.fail = false
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if len(rec.messages) != 1 || !strings.Contains(rec.messages[0], "New issues:") {
+		t.Fatalf("notifications = %q, want the alert that failed before", rec.messages)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{20 * time.Second, "less than a minute"},
+		{45 * time.Minute, "45m"},
+		{3*time.Hour + 12*time.Minute + 40*time.Second, "3h 13m"},
+		{51 * time.Hour, "2d 3h"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestRunOnceNotificationSeverity(t *testing.T) {
+	svc, _ := newTestService(t, "degraded")
+	rec := &recorder{}
+	svc.notifier = rec
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	r, err := zfstest.Scenario("healthy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovered := New(svc.cfg, r)
+	recovered.notifier = rec
+	if err := recovered.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	if len(rec.sent) != 2 {
+		t.Fatalf("sent %d notifications, want 2", len(rec.sent))
+	}
+	alert, resolved := rec.sent[0], rec.sent[1]
+	if alert.Severity != notify.Warning || !slices.Equal(alert.Pools, []string{"tank"}) ||
+		!slices.Equal(alert.Devices, []string{"sdb"}) {
+		t.Errorf("alert = %+v, want a warning about tank and sdb", alert)
+	}
+	if resolved.Severity != notify.Info || resolved.Title != "ZFSGuard Resolved" {
+		t.Errorf("resolved = %+v, want info", resolved)
+	}
+}
+
+func TestNotifyIssuesSplitsByRoute(t *testing.T) {
+	svc, _ := newTestService(t, "healthy")
+	rec := &recorder{routes: notify.New(config.NotifyConfig{Routes: []config.NotifyRoute{
+		{Pools: []string{"tank"}, URLs: []string{"ntfy://ntfy.sh/tank"}},
+		{Pools: []string{"backup"}, URLs: []string{"ntfy://ntfy.sh/backup"}},
+		{Severities: []string{"critical"}, URLs: []string{"pushover://pager"}},
+		{Severities: []string{"warning"}, URLs: []string{"ntfy://ntfy.sh/warnings"}},
+	}})}
+	svc.notifier = rec
+
+	critical := issue{Key: "pool:tank:state", Severity: notify.Critical, Pool: "tank",
+		Message: `ZFS: Pool "tank" is in state: FAULTED`}
+	warning := issue{Key: "pool:backup:state", Severity: notify.Warning, Pool: "backup",
+		Message: `ZFS: Pool "backup" is in state: DEGRADED`}
+	if err := svc.notifyIssues([]issue{critical, warning}); err != nil {
+		t.Fatalf("notifyIssues: %v", err)
+	}
+
+	if len(rec.sent) != 2 {
+		t.Fatalf("sent %d notifications, want one per route set: %q", len(rec.sent), rec.messages)
+	}
+	for _, tt := range []struct {
+		msg  notify.Message
+		want issue
+	}{{rec.sent[0], critical}, {rec.sent[1], warning}} {
+		if tt.msg.Severity != tt.want.Severity || !slices.Equal(tt.msg.Pools, []string{tt.want.Pool}) {
+			t.Errorf("notification = %+v, want %s about %s only", tt.msg, tt.want.Severity, tt.want.Pool)
+		}
+		if !strings.Contains(tt.msg.Body, tt.want.Message) || strings.Count(tt.msg.Body, "- ") != 1 {
+			t.Errorf("body = %q, want only %q", tt.msg.Body, tt.want.Message)
+		}
+	}
+}
+
+func TestNotifyIssuesRetriesFailedRouteOnly(t *testing.T) {
+	svc, _ := newTestService(t, "healthy")
+	failing := &failingRoute{recorder: recorder{routes: notify.New(config.NotifyConfig{Routes: []config.NotifyRoute{
+		{Pools: []string{"tank"}, URLs: []string{"ntfy://ntfy.sh/tank"}},
+	}})}, pool: "backup"}
+	svc.notifier = failing
+
+	tank := issue{Key: "pool:tank:state", Severity: notify.Warning, Pool: "tank", Message: "tank degraded"}
+	backup := issue{Key: "pool:backup:state", Severity: notify.Warning, Pool: "backup", Message: "backup degraded"}
+	if err := svc.notifyIssues([]issue{tank, backup}); err == nil {
+		t.Fatal("notifyIssues succeeded although one notification failed")
+	}
+	if _, ok := svc.alerts.Open[tank.Key]; !ok {
+		t.Error("delivered issue was not recorded")
+	}
+	if _, ok := svc.alerts.Open[backup.Key]; ok {
+		t.Error("undelivered issue was recorded and will not be retried")
+	}
+}
+
+// failingRoute is a recorder whose notifications about pool fail.
+type failingRoute struct {
+	recorder
+	pool string
+}
+
+func (f *failingRoute) Notify(m notify.Message) error {
+	if slices.Contains(m.Pools, f.pool) {
+		return errors.New("connection refused")
+	}
+	return f.recorder.Notify(m)
+}
@@ -0,0 +1,155 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/report"
+)
+
+// unixPrefix marks a listen address as the path of a Unix socket, e.g.
+// "unix:/run/zfsguard/api.sock".
+const unixPrefix = "unix:"
+
+// healthStatus is the body of /health.
+type healthStatus struct {
+	Healthy   bool      `json:"healthy"`
+	Timestamp time.Time `json:"timestamp,omitzero"`
+	// Problems lists the unhealthy pools and disks and failed checks.
+	Problems []string `json:"problems,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// APIHandler serves the health report of the last check cycle as JSON:
+//
+//	GET /health           overall status
+//	GET /report           the full health report
+//	GET /pools/{name}     one pool
+//	GET /disks/{device}   one disk, by path ("/dev/sda") or name ("sda")
+//
+// Responses are 200 when what they describe is healthy and 503 when it is
+// not or no check cycle has finished yet, so they can be used as probes.
+func (s *Service) APIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		rep, ok := s.lastReport()
+		if !ok {
+			writeAPIJSON(w, http.StatusServiceUnavailable, healthStatus{Error: "no check cycle has finished yet"})
+			return
+		}
+		st := healthStatus{Healthy: rep.Healthy(), Timestamp: rep.Timestamp, Problems: rep.Problems()}
+		writeAPIJSON(w, statusCode(st.Healthy), st)
+	})
+	mux.HandleFunc("GET /report", func(w http.ResponseWriter, r *http.Request) {
+		rep, ok := s.lastReport()
+		if !ok {
+			writeAPIError(w, http.StatusServiceUnavailable, "no check cycle has finished yet")
+			return
+		}
+		writeAPIJSON(w, statusCode(rep.Healthy()), rep)
+	})
+	mux.HandleFunc("GET /pools/{name}", func(w http.ResponseWriter, r *http.Request) {
+		rep, ok := s.lastReport()
+		if !ok {
+			writeAPIError(w, http.StatusServiceUnavailable, "no check cycle has finished yet")
+			return
+		}
+		name := r.PathValue("name")
+		for _, p := range rep.Pools {
+			if p.Name == name {
+				writeAPIJSON(w, statusCode(p.Healthy()), p)
+				return
+			}
+		}
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("pool %q not found", name))
+	})
+	mux.HandleFunc("GET /disks/{device...}", func(w http.ResponseWriter, r *http.Request) {
+		rep, ok := s.lastReport()
+		if !ok {
+			writeAPIError(w, http.StatusServiceUnavailable, "no check cycle has finished yet")
+			return
+		}
+		device := r.PathValue("device")
+		for _, d := range rep.Disks {
+			if d.Device == device || d.Device == "/"+device || path.Base(d.Device) == device {
+				writeAPIJSON(w, statusCode(d.Level() == report.LevelOK), d)
+				return
+			}
+		}
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("disk %q not found", device))
+	})
+	return mux
+}
+
+// lastReport returns the health report of the last check cycle.
+func (s *Service) lastReport() (report.HealthReport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil {
+		return report.HealthReport{}, false
+	}
+	return s.last.Report, true
+}
+
+func statusCode(healthy bool) int {
+	if healthy {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+
+func writeAPIJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Printf("Failed to write API response: %v", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, code int, msg string) {
+	writeAPIJSON(w, code, map[string]string{"error": msg})
+}
+
+// listen opens a TCP listener, or a Unix socket for "unix:<path>"; a stale
+// socket file left by a previous run is replaced.
+func listen(addr string) (net.Listener, error) {
+	if socket, ok := strings.CutPrefix(addr, unixPrefix); ok {
+		if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", socket, err)
+		}
+		l, err := net.Listen("unix", socket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", socket, err)
+		}
+		return l, nil
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return l, nil
+}
+
+// serve serves handler on addr in the background; name is used in logs.
+func serve(name, addr string, handler http.Handler) {
+	l, err := listen(addr)
+	if err != nil {
+		log.Printf("%s listener: %v", name, err)
+		return
+	}
+	log.Printf("Serving %s on %s", name, addr)
+	go func() {
+		if err := http.Serve(l, handler); err != nil {
+			log.Printf("%s listener: %v", name, err)
+		}
+	}()
+}
@@ -0,0 +1,148 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/report"
+)
+
+func apiGet(t *testing.T, svc *Service, target string) (int, []byte) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	svc.APIHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s: Content-Type = %q", target, ct)
+	}
+	return rec.Code, rec.Body.Bytes()
+}
+
+func TestAPIBeforeFirstCycle(t *testing.T) {
+	svc, _ := newTestService(t, "healthy")
+	for _, target := range []string{"/health", "/report", "/pools/tank", "/disks/sda"} {
+		if code, _ := apiGet(t, svc, target); code != http.StatusServiceUnavailable {
+			t.Errorf("%s = %d, want 503", target, code)
+		}
+	}
+}
+
+func TestAPI(t *testing.T) {
+	tests := []struct {
+		scenario string
+		target   string
+		code     int
+	}{
+		{"healthy", "/health", http.StatusOK},
+		{"healthy", "/report", http.StatusOK},
+		{"healthy", "/pools/tank", http.StatusOK},
+		{"healthy", "/pools/missing", http.StatusNotFound},
+		{"healthy", "/disks/sda", http.StatusOK},
+		{"healthy", "/disks/dev/sda", http.StatusOK},
+		{"healthy", "/disks/sdz", http.StatusNotFound},
+		{"degraded", "/health", http.StatusServiceUnavailable},
+		{"degraded", "/report", http.StatusServiceUnavailable},
+		{"degraded", "/pools/tank", http.StatusServiceUnavailable},
+		{"smart-failed", "/health", http.StatusServiceUnavailable},
+		{"smart-failed", "/pools/tank", http.StatusOK},
+		{"smart-failed", "/disks/sda", http.StatusOK},
+		{"smart-failed", "/disks/sdb", http.StatusServiceUnavailable},
+	}
+	services := map[string]*Service{}
+	for _, tt := range tests {
+		svc, ok := services[tt.scenario]
+		if !ok {
+			svc, _ = newTestService(t, tt.scenario)
+			if err := svc.RunOnce(); err != nil {
+				t.Fatalf("%s: RunOnce: %v", tt.scenario, err)
+			}
+			services[tt.scenario] = svc
+		}
+		code, body := apiGet(t, svc, tt.target)
+		if code != tt.code {
+			t.Errorf("%s %s = %d, want %d: %s", tt.scenario, tt.target, code, tt.code, body)
+		}
+	}
+}
+
+func TestAPIBodies(t *testing.T) {
+	svc, _ := newTestService(t, "smart-failed")
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	_, body := apiGet(t, svc, "/health")
+	var st healthStatus
+	if err := json.Unmarshal(body, &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Healthy || len(st.Problems) != 1 || st.Problems[0] != "disk /dev/sdb is critical" {
+		t.Errorf("health = %+v", st)
+	}
+
+	_, body = apiGet(t, svc, "/disks/sdb")
+	var d report.DiskReport
+	if err := json.Unmarshal(body, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Device != "/dev/sdb" || d.Level() != "critical" {
+		t.Errorf("disk = %+v", d)
+	}
+
+	_, body = apiGet(t, svc, "/pools/tank")
+	var p report.PoolReport
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "tank" || p.State != "ONLINE" || p.Size == 0 {
+		t.Errorf("pool = %+v", p)
+	}
+}
+
+func TestAPIUnixSocket(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, too short for t.TempDir.
+	dir, err := os.MkdirTemp("", "zfsguard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "api.sock")
+	// A stale socket file from a previous run is replaced.
+	if err := os.WriteFile(socket, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	svc, _ := newTestService(t, "healthy")
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	l, err := listen(unixPrefix + socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &http.Server{Handler: svc.APIHandler()}
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(func() { srv.Close() })
+
+	client := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://zfsguard/health")
+	if err != nil {
+		t.Fatalf("GET /health: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d: %s", resp.StatusCode, body)
+	}
+}
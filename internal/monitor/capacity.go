@@ -0,0 +1,62 @@
+package monitor
+
+import (
+	"fmt"
+
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/notify"
+	"github.com/pbek/zfsguard/internal/report"
+)
+
+// GradeCapacity sets the CapacityHealth of every pool of the report whose
+// size is known: critical at or above monitor.capacity_critical percent,
+// warning at or above monitor.capacity_warning, ok otherwise.
+func GradeCapacity(r *report.HealthReport, cfg config.MonitorConfig) {
+	for i := range r.Pools {
+		p := &r.Pools[i]
+		if p.Size == 0 {
+			continue
+		}
+		switch {
+		case cfg.CapacityCritical > 0 && p.Capacity >= cfg.CapacityCritical:
+			p.CapacityHealth = report.LevelCritical
+		case cfg.CapacityWarning > 0 && p.Capacity >= cfg.CapacityWarning:
+			p.CapacityHealth = report.LevelWarning
+		default:
+			p.CapacityHealth = report.LevelOK
+		}
+	}
+}
+
+// capacityIssues returns an issue for every graded pool above a capacity
+// threshold, e.g. `ZFS: Pool "tank" is 85% full (warning at 80%)`.
+func capacityIssues(pools []report.PoolReport, cfg config.MonitorConfig) []issue {
+	var issues []issue
+	for _, p := range pools {
+		var threshold int
+		switch p.CapacityHealth {
+		case report.LevelCritical:
+			threshold = cfg.CapacityCritical
+		case report.LevelWarning:
+			threshold = cfg.CapacityWarning
+		default:
+			continue
+		}
+		issues = append(issues, issue{Key: "pool:" + p.Name + ":capacity",
+			Severity: levelSeverity(p.CapacityHealth), Pool: p.Name,
+			Message: fmt.Sprintf("ZFS: Pool %q is %d%% full (%s at %d%%)",
+				p.Name, p.Capacity, p.CapacityHealth, threshold)})
+	}
+	return issues
+}
+
+// levelSeverity returns the notification severity of a health level.
+func levelSeverity(l report.Level) notify.Severity {
+	switch l {
+	case report.LevelOK:
+		return notify.Info
+	case report.LevelWarning:
+		return notify.Warning
+	}
+	return notify.Critical
+}
@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// heartbeatTimeout bounds a heartbeat ping, so an unreachable ping service
+// cannot hold up the check cycle.
+const heartbeatTimeout = 10 * time.Second
+
+// sendHeartbeat tells the configured heartbeat services that a check cycle
+// ran, and whether it found issues or failed to notify about them. Failed
+// pings are logged; the external service alerts when they stop arriving.
+func (s *Service) sendHeartbeat(issues []issue, notifyErr error) {
+	hb := s.cfg.Monitor.Heartbeat
+	if hb.URL == "" && hb.ShoutrrrURL == "" {
+		return
+	}
+	var problems []string
+	for _, i := range issues {
+		problems = append(problems, i.Message)
+	}
+	if notifyErr != nil {
+		problems = append(problems, fmt.Sprintf("Failed to send notifications: %v", notifyErr))
+	}
+
+	if hb.URL != "" {
+		if err := pingURL(hb.URL, len(problems) > 0); err != nil {
+			log.Printf("Heartbeat failed: %v", err)
+		}
+	}
+	if hb.ShoutrrrURL != "" {
+		if err := s.sendURL(hb.ShoutrrrURL, heartbeatMessage(problems)); err != nil {
+			log.Printf("Heartbeat failed: %v", err)
+		}
+	}
+}
+
+// pingURL sends a GET to rawURL, or to its "/fail" endpoint when failed.
+func pingURL(rawURL string, failed bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid heartbeat URL: %w", err)
+	}
+	if failed {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/fail"
+	}
+	client := &http.Client{Timeout: heartbeatTimeout}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		// The error includes the URL, which usually holds a secret token.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("failed to ping heartbeat URL: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to ping heartbeat URL: %s", resp.Status)
+	}
+	return nil
+}
+
+// heartbeatMessage is the Shoutrrr heartbeat text, e.g. "ZFSGuard
+// heartbeat from nas: all checks passed".
+func heartbeatMessage(problems []string) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown host"
+	}
+	if len(problems) == 0 {
+		return fmt.Sprintf("ZFSGuard heartbeat from %s: all checks passed", host)
+	}
+	return fmt.Sprintf("ZFSGuard heartbeat from %s: %d problem(s)\n- %s",
+		host, len(problems), strings.Join(problems, "\n- "))
+}
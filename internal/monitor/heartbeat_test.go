@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHeartbeatURL(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path+"?"+r.URL.RawQuery)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		scenario string
+		want     string
+	}{
+		{"healthy", "/ping/abc?"},
+		{"degraded", "/ping/abc/fail?"},
+		{"smart-failed", "/ping/abc/fail?"},
+	}
+	for _, tt := range tests {
+		svc, _ := newTestService(t, tt.scenario)
+		svc.cfg.Monitor.Heartbeat.URL = srv.URL + "/ping/abc"
+		if err := svc.RunOnce(); err != nil {
+			t.Fatalf("%s: RunOnce: %v", tt.scenario, err)
+		}
+		mu.Lock()
+		if len(paths) != 1 || paths[0] != tt.want {
+			t.Errorf("%s: pinged %q, want %q", tt.scenario, paths, tt.want)
+		}
+		paths = nil
+		mu.Unlock()
+	}
+
+	// Query parameters are kept when /fail is appended.
+	if err := pingURL(srv.URL+"/ping/abc/?rid=1", true); err != nil {
+		t.Fatal(err)
+	}
+	if paths[0] != "/ping/abc/fail?rid=1" {
+		t.Errorf("pinged %q", paths)
+	}
+
+	status = http.StatusNotFound
+	if err := pingURL(srv.URL+"/ping/abc", false); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("ping of a missing check = %v", err)
+	}
+}
+
+func TestHeartbeatFailsWhenNotificationsFail(t *testing.T) {
+	svc, _ := newTestService(t, "degraded")
+	svc.notifier = &recorder{fail: true}
+	var messages []string
+	svc.sendURL = func(url, message string) error {
+		messages = append(messages, message)
+		return nil
+	}
+	svc.cfg.Monitor.Heartbeat.ShoutrrrURL = "generic://example.com/heartbeat"
+
+	if err := svc.RunOnce(); err == nil {
+		t.Fatal("RunOnce succeeded although notifications failed")
+	}
+	if len(messages) != 1 || !strings.Contains(messages[0], "problem(s)") ||
+		!strings.Contains(messages[0], "- Failed to send notifications: ") {
+		t.Errorf("heartbeat = %q", messages)
+	}
+}
+
+func TestHeartbeatShoutrrr(t *testing.T) {
+	svc, _ := newTestService(t, "healthy")
+	var urls, messages []string
+	svc.sendURL = func(url, message string) error {
+		urls = append(urls, url)
+		messages = append(messages, message)
+		return nil
+	}
+	svc.cfg.Monitor.Heartbeat.ShoutrrrURL = "ntfy://ntfy.sh/zfsguard-heartbeat"
+
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if len(urls) != 1 || urls[0] != "ntfy://ntfy.sh/zfsguard-heartbeat" ||
+		!strings.HasSuffix(messages[0], ": all checks passed") {
+		t.Errorf("heartbeat = %q %q", urls, messages)
+	}
+}
@@ -0,0 +1,318 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/report"
+	"github.com/pbek/zfsguard/internal/zfs"
+)
+
+// cycle is the outcome of a check cycle, kept for the metrics.
+type cycle struct {
+	Time   time.Time
+	Report report.HealthReport
+	// Snapshots are only listed when metrics are enabled.
+	Snapshots []zfs.Snapshot
+	// Checks are the checks that ran, in order.
+	Checks []checkResult
+}
+
+// checkResult is the duration and outcome of one check of a cycle.
+type checkResult struct {
+	Name     string // "zfs", "smart", "prune" or "snapshots"
+	Duration time.Duration
+	OK       bool
+}
+
+// metricsEnabled reports whether the cycles are kept for metrics.
+func (s *Service) metricsEnabled() bool {
+	return s.cfg.Monitor.MetricsAddress != "" || s.cfg.Monitor.TextfilePath != ""
+}
+
+// timeCheck runs a check and records its duration and outcome in c.
+func (s *Service) timeCheck(c *cycle, name string, check func() error) {
+	start := s.now()
+	err := check()
+	c.Checks = append(c.Checks, checkResult{Name: name, Duration: s.now().Sub(start), OK: err == nil})
+}
+
+// MetricsHandler serves the metrics of the last check cycle in the
+// Prometheus text exposition format. It answers 503 until the first cycle
+// has finished.
+func (s *Service) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		last := s.last
+		s.mu.Unlock()
+		if last == nil {
+			http.Error(w, "no check cycle has finished yet", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := writeMetrics(w, *last, s.now()); err != nil {
+			log.Printf("Failed to write metrics: %v", err)
+		}
+	})
+}
+
+// serveMetrics starts the metrics listener in the background.
+func (s *Service) serveMetrics() {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.MetricsHandler())
+	serve("metrics", s.cfg.Monitor.MetricsAddress, mux)
+}
+
+// writeTextfile atomically writes the metrics of a check cycle to path for
+// node_exporter's textfile collector, which must never see a partial file.
+func writeTextfile(path string, c cycle, now time.Time) error {
+	var b strings.Builder
+	if err := writeMetrics(&b, c, now); err != nil {
+		return fmt.Errorf("failed to render metrics: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create textfile directory %s: %w", dir, err)
+	}
+
+	// The collector only reads *.prom files, so the temporary file is
+	// ignored until it is renamed.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write metrics textfile: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to rename metrics textfile: %w", err)
+	}
+	return nil
+}
+
+// metricFamily is a gauge with its samples, written together as the
+// exposition format requires.
+type metricFamily struct {
+	name, help string
+	samples    []metricSample
+}
+
+type metricSample struct {
+	labels []string // name, value, name, value, ...
+	value  float64
+}
+
+// metrics collects metric families in the order they are first used.
+type metrics struct {
+	families []*metricFamily
+	byName   map[string]*metricFamily
+}
+
+// add records a sample of the gauge name; labels are name/value pairs.
+func (m *metrics) add(name, help string, value float64, labels ...string) {
+	if m.byName == nil {
+		m.byName = map[string]*metricFamily{}
+	}
+	f, ok := m.byName[name]
+	if !ok {
+		f = &metricFamily{name: name, help: help}
+		m.byName[name] = f
+		m.families = append(m.families, f)
+	}
+	f.samples = append(f.samples, metricSample{labels: labels, value: value})
+}
+
+func (m *metrics) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range m.families {
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(bw, "# TYPE %s gauge\n", f.name)
+		for _, s := range f.samples {
+			bw.WriteString(f.name)
+			if len(s.labels) > 0 {
+				bw.WriteString("{")
+				for i := 0; i+1 < len(s.labels); i += 2 {
+					if i > 0 {
+						bw.WriteString(",")
+					}
+					fmt.Fprintf(bw, "%s=\"%s\"", s.labels[i], escapeLabel(s.labels[i+1]))
+				}
+				bw.WriteString("}")
+			}
+			bw.WriteString(" " + strconv.FormatFloat(s.value, 'f', -1, 64) + "\n")
+		}
+	}
+	return bw.Flush()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// diskHealthValues are the values of zfsguard_disk_health.
+var diskHealthValues = map[report.Level]float64{
+	report.LevelOK:       0,
+	report.LevelWarning:  1,
+	report.LevelCritical: 2,
+}
+
+// writeMetrics renders the metrics of a check cycle. Snapshot ages are
+// relative to now.
+func writeMetrics(w io.Writer, c cycle, now time.Time) error {
+	var m metrics
+
+	m.add("zfsguard_last_check_timestamp_seconds", "Unix time of the last check cycle.",
+		float64(c.Time.Unix()))
+	for _, check := range c.Checks {
+		m.add("zfsguard_check_duration_seconds", "Duration of each check of the last cycle.",
+			check.Duration.Seconds(), "check", check.Name)
+		m.add("zfsguard_check_success", "Whether each check of the last cycle succeeded.",
+			boolValue(check.OK), "check", check.Name)
+	}
+
+	for _, p := range c.Report.Pools {
+		poolMetrics(&m, p)
+	}
+	for _, d := range c.Report.Disks {
+		diskMetrics(&m, d)
+	}
+
+	datasets := snapshotStats(c.Snapshots)
+	names := make([]string, 0, len(datasets))
+	for name := range datasets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		st := datasets[name]
+		m.add("zfsguard_dataset_snapshots", "Number of snapshots of the dataset.",
+			float64(st.count), "dataset", name)
+		m.add("zfsguard_dataset_newest_snapshot_timestamp_seconds",
+			"Unix creation time of the newest snapshot of the dataset.",
+			float64(st.newest.Unix()), "dataset", name)
+		m.add("zfsguard_dataset_newest_snapshot_age_seconds", "Age of the newest snapshot of the dataset.",
+			now.Sub(st.newest).Seconds(), "dataset", name)
+		m.add("zfsguard_dataset_oldest_snapshot_timestamp_seconds",
+			"Unix creation time of the oldest snapshot of the dataset.",
+			float64(st.oldest.Unix()), "dataset", name)
+	}
+
+	return m.write(w)
+}
+
+func poolMetrics(m *metrics, p report.PoolReport) {
+	m.add("zfsguard_pool_healthy", "Whether the pool is ONLINE without errors or faulted devices and below the capacity thresholds.",
+		boolValue(p.Healthy()), "pool", p.Name)
+	m.add("zfsguard_pool_state", "State of the pool; the sample with the current state is 1.",
+		1, "pool", p.Name, "state", p.State)
+	if p.Size > 0 {
+		m.add("zfsguard_pool_size_bytes", "Size of the pool.", float64(p.Size), "pool", p.Name)
+		m.add("zfsguard_pool_allocated_bytes", "Allocated space of the pool.", float64(p.Allocated), "pool", p.Name)
+		m.add("zfsguard_pool_free_bytes", "Free space of the pool.", float64(p.Free), "pool", p.Name)
+		m.add("zfsguard_pool_capacity_percent", "Allocated share of the pool in percent.",
+			float64(p.Capacity), "pool", p.Name)
+		m.add("zfsguard_pool_fragmentation_percent", "Free space fragmentation of the pool in percent.",
+			float64(p.Fragmentation), "pool", p.Name)
+		m.add("zfsguard_pool_dedup_ratio", "Deduplication ratio of the pool.", p.DedupRatio, "pool", p.Name)
+	}
+
+	var walk func(vdevs []report.VdevReport)
+	walk = func(vdevs []report.VdevReport) {
+		for _, v := range vdevs {
+			// Section headers (logs, cache, ...) have no state or counters.
+			if v.State != "" {
+				help := "Error counters of the vdevs as reported by zpool status."
+				m.add("zfsguard_vdev_errors", help, float64(v.Read), "pool", p.Name, "vdev", v.Name, "type", "read")
+				m.add("zfsguard_vdev_errors", help, float64(v.Write), "pool", p.Name, "vdev", v.Name, "type", "write")
+				m.add("zfsguard_vdev_errors", help, float64(v.Checksum), "pool", p.Name, "vdev", v.Name, "type", "checksum")
+			}
+			walk(v.Children)
+		}
+	}
+	walk(p.Vdevs)
+}
+
+func diskMetrics(m *metrics, d report.DiskReport) {
+	m.add("zfsguard_disk_health", "SMART health of the disk: 0 ok, 1 warning, 2 critical.",
+		diskHealthValues[d.Level()], "device", d.Device)
+	a := d.Attributes
+	if a == nil {
+		return
+	}
+	if a.Temperature != 0 {
+		m.add("zfsguard_disk_temperature_celsius", "Current temperature of the disk.",
+			float64(a.Temperature), "device", d.Device)
+	}
+	if a.PowerOnHours != 0 {
+		m.add("zfsguard_disk_power_on_hours", "Power-on hours of the disk.",
+			float64(a.PowerOnHours), "device", d.Device)
+	}
+	for _, attr := range a.ATA {
+		labels := []string{"device", d.Device, "id", strconv.Itoa(attr.ID), "attribute", attr.Name}
+		m.add("zfsguard_smart_attribute_value", "Normalized value of the ATA SMART attributes.",
+			float64(attr.Value), labels...)
+		m.add("zfsguard_smart_attribute_threshold", "Failure threshold of the ATA SMART attributes.",
+			float64(attr.Threshold), labels...)
+		m.add("zfsguard_smart_attribute_raw", "Raw value of the ATA SMART attributes.",
+			float64(attr.Raw), labels...)
+	}
+	if n := a.NVMe; n != nil {
+		m.add("zfsguard_nvme_critical_warning", "Critical warning bit mask of the NVMe health log.",
+			float64(n.CriticalWarning), "device", d.Device)
+		m.add("zfsguard_nvme_available_spare_percent", "Available spare of the NVMe device.",
+			float64(n.AvailableSpare), "device", d.Device)
+		m.add("zfsguard_nvme_percentage_used", "Estimated wear of the NVMe device in percent.",
+			float64(n.PercentageUsed), "device", d.Device)
+		m.add("zfsguard_nvme_media_errors", "Media and data integrity errors of the NVMe device.",
+			float64(n.MediaErrors), "device", d.Device)
+		m.add("zfsguard_nvme_error_log_entries", "Error log entries of the NVMe device.",
+			float64(n.ErrorLogEntries), "device", d.Device)
+		m.add("zfsguard_nvme_unsafe_shutdowns", "Unsafe shutdowns of the NVMe device.",
+			float64(n.UnsafeShutdowns), "device", d.Device)
+	}
+	if c := a.SCSI; c != nil {
+		help := "Uncorrected errors of the SCSI error counter log."
+		m.add("zfsguard_scsi_uncorrected_errors", help, float64(c.ReadUncorrected), "device", d.Device, "type", "read")
+		m.add("zfsguard_scsi_uncorrected_errors", help, float64(c.WriteUncorrected), "device", d.Device, "type", "write")
+		m.add("zfsguard_scsi_uncorrected_errors", help, float64(c.VerifyUncorrected), "device", d.Device, "type", "verify")
+		m.add("zfsguard_scsi_grown_defects", "Size of the SCSI grown defect list.",
+			float64(c.GrownDefects), "device", d.Device)
+	}
+}
+
+type datasetSnapshots struct {
+	count          int
+	oldest, newest time.Time
+}
+
+func snapshotStats(snaps []zfs.Snapshot) map[string]datasetSnapshots {
+	stats := map[string]datasetSnapshots{}
+	for _, snap := range snaps {
+		st, ok := stats[snap.Dataset]
+		st.count++
+		if !ok || snap.Creation.Before(st.oldest) {
+			st.oldest = snap.Creation
+		}
+		if !ok || snap.Creation.After(st.newest) {
+			st.newest = snap.Creation
+		}
+		stats[snap.Dataset] = st
+	}
+	return stats
+}
@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, svc *Service) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	svc.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		scenario string
+		want     []string
+	}{
+		{"healthy", []string{
+			`zfsguard_check_success{check="zfs"} 1`,
+			`zfsguard_check_success{check="smart"} 1`,
+			`zfsguard_pool_healthy{pool="tank"} 1`,
+			`zfsguard_pool_state{pool="tank",state="ONLINE"} 1`,
+			`zfsguard_pool_size_bytes{pool="tank"} 3985729650688`,
+			`zfsguard_pool_capacity_percent{pool="tank"} 31`,
+			`zfsguard_vdev_errors{pool="tank",vdev="sda",type="checksum"} 0`,
+			`zfsguard_disk_health{device="/dev/sda"} 0`,
+			`zfsguard_dataset_snapshots{dataset="tank/data"} 2`,
+			`zfsguard_dataset_snapshots{dataset="tank/home"} 1`,
+			`zfsguard_dataset_newest_snapshot_timestamp_seconds{dataset="tank/data"} 1770508800`,
+			`zfsguard_dataset_oldest_snapshot_timestamp_seconds{dataset="tank/data"} 1769904000`,
+		}},
+		{"degraded", []string{
+			`zfsguard_pool_healthy{pool="tank"} 0`,
+			`zfsguard_pool_state{pool="tank",state="DEGRADED"} 1`,
+		}},
+		{"smart-worn", []string{
+			`zfsguard_disk_health{device="/dev/sda"} 2`,
+			`zfsguard_disk_temperature_celsius{device="/dev/sda"} 58`,
+			`zfsguard_disk_power_on_hours{device="/dev/sda"} 52311`,
+			`zfsguard_smart_attribute_raw{device="/dev/sda",id="5",attribute="Reallocated_Sector_Ct"} 8`,
+			`zfsguard_nvme_percentage_used{device="/dev/nvme0"} 86`,
+			`zfsguard_nvme_unsafe_shutdowns{device="/dev/nvme0"} 41`,
+			`zfsguard_scsi_grown_defects{device="/dev/sdc"} 2`,
+			`zfsguard_scsi_uncorrected_errors{device="/dev/sdc",type="verify"} 1`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scenario, func(t *testing.T) {
+			svc, _ := newTestService(t, tt.scenario)
+			svc.cfg.Monitor.MetricsAddress = "127.0.0.1:0"
+
+			if code, _ := scrape(t, svc); code != http.StatusServiceUnavailable {
+				t.Errorf("status before the first cycle = %d, want 503", code)
+			}
+			if err := svc.RunOnce(); err != nil {
+				t.Fatalf("RunOnce: %v", err)
+			}
+			code, body := scrape(t, svc)
+			if code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", code)
+			}
+			lines := strings.Split(body, "\n")
+			for _, want := range tt.want {
+				found := false
+				for _, l := range lines {
+					if l == want {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("missing %q in:\n%s", want, body)
+				}
+			}
+			if !strings.Contains(body, "# TYPE zfsguard_last_check_timestamp_seconds gauge\n") {
+				t.Errorf("missing TYPE line in:\n%s", body)
+			}
+		})
+	}
+}
+
+func TestMetricsFamiliesGrouped(t *testing.T) {
+	var m metrics
+	m.add("a", "A.", 1, "x", "1")
+	m.add("b", "B.", 2)
+	m.add("a", "A.", 3, "x", "a\"b\\c\nd")
+	var b strings.Builder
+	if err := m.write(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP a A.\n# TYPE a gauge\na{x=\"1\"} 1\na{x=\"a\\\"b\\\\c\\nd\"} 3\n" +
+		"# HELP b B.\n# TYPE b gauge\nb 2\n"
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestRunOnceWritesTextfile(t *testing.T) {
+	svc, path := newTestService(t, "healthy")
+	textfile := filepath.Join(filepath.Dir(path), "textfile", "zfsguard.prom")
+	svc.cfg.Monitor.TextfilePath = textfile
+
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	data, err := os.ReadFile(textfile)
+	if err != nil {
+		t.Fatalf("textfile not written: %v", err)
+	}
+	for _, want := range []string{
+		"# TYPE zfsguard_pool_healthy gauge\n",
+		`zfsguard_pool_healthy{pool="tank"} 1` + "\n",
+		`zfsguard_dataset_snapshots{dataset="tank/data"} 2` + "\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("missing %q in:\n%s", want, data)
+		}
+	}
+	if _, err := os.Stat(textfile + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
@@ -2,111 +2,408 @@
 package monitor
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pbek/zfsguard/internal/config"
 	"github.com/pbek/zfsguard/internal/notify"
 	"github.com/pbek/zfsguard/internal/report"
+	"github.com/pbek/zfsguard/internal/retention"
 	"github.com/pbek/zfsguard/internal/zfs"
 )
 
 // Service is the monitoring service that checks ZFS and SMART health.
 type Service struct {
 	cfg      config.Config
-	notifier *notify.Notifier
+	notifier sender
+	zfs      *zfs.Client
+	now      func() time.Time
+	// sendURL sends a message to one Shoutrrr URL; notify.SendURL in
+	// production.
+	sendURL func(url, message string) error
+
+	// alerts are the open issues; loaded from the state file on the first
+	// check cycle.
+	alerts       alertState
+	alertsLoaded bool
+
+	// mu guards last, the latest check cycle served as metrics and by
+	// the status API.
+	mu   sync.Mutex
+	last *cycle
+}
+
+// sender delivers notifications; *notify.Notifier in production.
+type sender interface {
+	Notify(m notify.Message) error
+	// RouteKey identifies the routes a message matches.
+	RouteKey(m notify.Message) string
 }
 
-// New creates a new monitoring service.
-func New(cfg config.Config) *Service {
+// New creates a new monitoring service. All zfs, zpool and smartctl calls
+// are executed through runner; a nil runner uses the local system.
+func New(cfg config.Config, runner zfs.Runner) *Service {
 	return &Service{
 		cfg:      cfg,
 		notifier: notify.New(cfg.Notify),
+		zfs:      zfs.NewClient(runner),
+		now:      time.Now,
+		sendURL:  notify.SendURL,
 	}
 }
 
-// RunOnce performs a single health check cycle.
+// RunOnce performs a single health check cycle. Issues are only notified
+// when they are new, escalate, resolve or are due for a reminder (see
+// notify.renotify_interval). The cycle ends with a ping of the configured
+// heartbeat (monitor.heartbeat).
 func (s *Service) RunOnce() error {
 	log.Println("Running health check...")
 
-	var issues []string
+	var issues []issue
 	var pools []zfs.PoolStatus
+	var usages []zfs.PoolUsage
 	var disks []zfs.SMARTStatus
 	var poolErr, diskErr error
+	c := cycle{Time: s.now()}
 
 	if s.cfg.Monitor.CheckZFS {
-		pools, poolErr = zfs.PoolStatuses()
+		s.timeCheck(&c, "zfs", func() error {
+			pools, poolErr = s.zfs.PoolStatuses()
+			if poolErr == nil {
+				// Usage is informational; a failure does not fail the check.
+				usages, _ = s.zfs.PoolUsages()
+			}
+			return poolErr
+		})
 		if poolErr != nil {
 			log.Printf("ZFS check error: %v", poolErr)
-			issues = append(issues, fmt.Sprintf("ZFS check failed: %v", poolErr))
+			issues = append(issues, issue{Key: "zfs", Severity: notify.Critical,
+				Message: fmt.Sprintf("ZFS check failed: %v", poolErr)})
+		} else if found := poolIssues(pools); len(found) > 0 {
+			for _, i := range found {
+				log.Printf("ZFS issues found: %s", i.Message)
+			}
+			issues = append(issues, found...)
 		} else {
-			hasErrors, summary, err := zfs.CheckZFSErrors()
-			if err != nil {
-				log.Printf("ZFS check error: %v", err)
-				poolErr = err
-				issues = append(issues, fmt.Sprintf("ZFS check failed: %v", err))
-			} else if hasErrors {
-				log.Printf("ZFS issues found: %s", summary)
-				issues = append(issues, "ZFS: "+summary)
-			} else {
-				log.Printf("ZFS: %s", summary)
+			log.Println("ZFS: All pools healthy")
+		}
+		if poolErr == nil {
+			for _, i := range scrubIssues(pools, s.scrubMaxAge(), c.Time) {
+				log.Printf("ZFS issues found: %s", i.Message)
+				issues = append(issues, i)
 			}
 		}
 	}
 
 	if s.cfg.Monitor.CheckSMART {
-		disks, diskErr = zfs.CheckSMART(s.cfg.Monitor.SMARTDevices)
+		s.timeCheck(&c, "smart", func() error {
+			disks, diskErr = s.CheckSMART()
+			return diskErr
+		})
 		if diskErr != nil {
 			log.Printf("SMART check error: %v", diskErr)
-			issues = append(issues, fmt.Sprintf("SMART check failed: %v", diskErr))
+			issues = append(issues, issue{Key: "smart", Severity: notify.Warning,
+				Message: fmt.Sprintf("SMART check failed: %v", diskErr)})
 		} else {
 			// Check for unhealthy disks
+			healthy := true
 			for _, d := range disks {
-				if !d.Healthy {
-					summary := fmt.Sprintf("Device %s: %s", d.Device, d.Summary)
+				if !d.Healthy() {
+					healthy = false
+					summary := fmt.Sprintf("%s [%s]", d.Describe(), d.Health)
 					log.Printf("SMART issues found: %s", summary)
-					issues = append(issues, "SMART: "+summary)
+					issues = append(issues, issue{Key: "smart:" + d.Device, Severity: levelSeverity(report.SMARTLevel(d.Health)),
+						Message: "SMART: " + summary, Device: d.Device})
 				}
 			}
-			if len(issues) == 0 || !containsSMART(issues) {
+			if healthy {
 				log.Println("SMART: All disks healthy")
 			}
 		}
 	}
 
+	var pruning *report.PruneReport
+	if len(s.cfg.Retention.Datasets) > 0 {
+		var err error
+		s.timeCheck(&c, "prune", func() error {
+			pruning, err = s.Prune(s.cfg.Retention.DryRun)
+			return err
+		})
+		if err != nil {
+			log.Printf("Pruning error: %v", err)
+			issues = append(issues, issue{Key: "prune", Severity: notify.Warning,
+				Message: fmt.Sprintf("Pruning failed: %v", err)})
+		}
+		for _, f := range pruning.Failed {
+			issues = append(issues, issue{Key: "prune:" + f.Snapshot, Severity: notify.Warning,
+				Message: fmt.Sprintf("Pruning: %s", f.Error), Pool: poolOf(f.Snapshot)})
+		}
+	}
+
+	if s.metricsEnabled() {
+		s.timeCheck(&c, "snapshots", func() error {
+			var err error
+			c.Snapshots, err = s.zfs.ListSnapshots()
+			return err
+		})
+	}
+
+	r := report.FromChecks(pools, poolErr, disks, diskErr)
+	r.SetUsage(usages)
+	GradeCapacity(&r, s.cfg.Monitor)
+	r.Pruning = pruning
+	for _, i := range capacityIssues(r.Pools, s.cfg.Monitor) {
+		log.Printf("ZFS issues found: %s", i.Message)
+		issues = append(issues, i)
+	}
+	c.Report = r
+	s.mu.Lock()
+	s.last = &c
+	s.mu.Unlock()
+	if path := s.cfg.Monitor.TextfilePath; path != "" {
+		if err := writeTextfile(path, c, s.now()); err != nil {
+			log.Printf("Failed to write metrics: %v", err)
+		} else {
+			log.Printf("Metrics written to %s", path)
+		}
+	}
+
 	// Write health report to disk
 	if s.cfg.Monitor.ReportPath != "" {
-		r := report.FromChecks(pools, poolErr, disks, diskErr)
 		if err := report.Write(s.cfg.Monitor.ReportPath, r); err != nil {
 			log.Printf("Failed to write health report: %v", err)
 		} else {
 			log.Printf("Health report written to %s", s.cfg.Monitor.ReportPath)
 		}
+		if days := s.cfg.Monitor.HistoryDays; days > 0 {
+			path := report.HistoryPath(s.cfg.Monitor.ReportPath)
+			if err := report.AppendHistory(path, r, time.Duration(days)*24*time.Hour); err != nil {
+				log.Printf("Failed to append to report history: %v", err)
+			}
+		}
 	}
 
-	if len(issues) > 0 {
-		msg := strings.Join(issues, "\n") + "\n"
-		if err := s.notifier.Send("ZFSGuard Alert", msg); err != nil {
-			log.Printf("Failed to send notification: %v", err)
-			return err
-		}
-		log.Println("Alert notification sent")
-	} else {
+	if len(issues) == 0 {
 		log.Println("All checks passed")
 	}
+	err := s.notifyIssues(issues)
+	s.sendHeartbeat(issues, err)
+	return err
+}
 
-	return nil
+// errSMARTNeedsRoot is the SMART error of a live report run without root.
+var errSMARTNeedsRoot = errors.New("SMART data is unavailable without root; run as root or read the monitor's report")
+
+// LiveReport runs the ZFS and SMART checks enabled in the config and
+// returns their report, like one check cycle without notifications,
+// pruning or writing anything. Without root, pool health is still checked
+// and SMART is reported as unavailable.
+func (s *Service) LiveReport() report.HealthReport {
+	var pools []zfs.PoolStatus
+	var usages []zfs.PoolUsage
+	var disks []zfs.SMARTStatus
+	var poolErr, diskErr error
+	if s.cfg.Monitor.CheckZFS {
+		pools, poolErr = s.zfs.PoolStatuses()
+		if poolErr == nil {
+			usages, _ = s.zfs.PoolUsages()
+		}
+	}
+	if s.cfg.Monitor.CheckSMART {
+		if s.zfs.IsRoot() {
+			disks, diskErr = s.CheckSMART()
+		} else {
+			diskErr = errSMARTNeedsRoot
+		}
+	}
+	r := report.FromChecks(pools, poolErr, disks, diskErr)
+	r.SetUsage(usages)
+	GradeCapacity(&r, s.cfg.Monitor)
+	return r
 }
 
-func containsSMART(issues []string) bool {
-	for _, s := range issues {
-		if strings.HasPrefix(s, "SMART:") {
-			return true
+// poolIssues returns the problems of the pools: a state other than ONLINE
+// (critical, DEGRADED is a warning), faulted devices or devices with errors
+// (warning) and data errors (critical).
+func poolIssues(pools []zfs.PoolStatus) []issue {
+	var issues []issue
+	for _, p := range pools {
+		key := "pool:" + p.Name
+		if p.State != "ONLINE" {
+			severity := notify.Critical
+			if p.State == "DEGRADED" {
+				severity = notify.Warning
+			}
+			issues = append(issues, issue{Key: key + ":state", Severity: severity, Pool: p.Name,
+				Message: fmt.Sprintf("ZFS: Pool %q is in state: %s", p.Name, p.State)})
+		}
+		for _, d := range p.FaultedDevices() {
+			issues = append(issues, issue{Key: key + ":device:" + d.Name, Severity: notify.Warning,
+				Pool: p.Name, Device: d.Name,
+				Message: fmt.Sprintf("ZFS: Pool %q device %s", p.Name, d.Describe())})
+		}
+		if p.Errors != "" && p.Errors != "No known data errors" {
+			issues = append(issues, issue{Key: key + ":errors", Severity: notify.Critical, Pool: p.Name,
+				Message: fmt.Sprintf("ZFS: Pool %q has errors: %s", p.Name, p.Errors)})
 		}
 	}
-	return false
+	return issues
+}
+
+// poolOf returns the pool of a dataset or snapshot name.
+func poolOf(name string) string {
+	pool, _, _ := strings.Cut(name, "/")
+	pool, _, _ = strings.Cut(pool, "@")
+	return pool
+}
+
+// notifyIssues sends the changes since the last cycle and records the new
+// alert state once they are delivered.
+func (s *Service) notifyIssues(issues []issue) error {
+	statePath := ""
+	if s.cfg.Monitor.ReportPath != "" {
+		statePath = filepath.Join(filepath.Dir(s.cfg.Monitor.ReportPath), alertStateFile)
+	}
+	if !s.alertsLoaded {
+		s.alerts = alertState{Open: map[string]openIssue{}}
+		if statePath != "" {
+			st, err := loadAlertState(statePath)
+			if err != nil {
+				log.Printf("Alert state: %v", err)
+			}
+			s.alerts = st
+		}
+		s.alertsLoaded = true
+	}
+
+	now := s.now()
+	update, next := s.alerts.update(now, issues, s.renotifyInterval())
+	if update.empty() {
+		if len(issues) > 0 {
+			log.Printf("%d open issue(s) already notified", len(issues))
+		}
+		s.alerts = next
+		return nil
+	}
+
+	// Issues are sent in one notification per set of matching routes, so a
+	// route only receives its own issues, and resolved and open issues are
+	// separate notifications. Each change is only recorded once its
+	// notification has been delivered, so failed ones are sent again next
+	// cycle.
+	st := alertState{Open: make(map[string]openIssue, len(s.alerts.Open))}
+	for key, o := range s.alerts.Open {
+		st.Open[key] = o
+	}
+	var sendErr error
+	failed := map[string]bool{}
+	for _, part := range update.split(s.notifier.RouteKey) {
+		if len(part.Resolved) > 0 {
+			if err := s.notifier.Notify(part.resolved(now)); err != nil {
+				log.Printf("Failed to send notification: %v", err)
+				sendErr = err
+			} else {
+				log.Printf("Resolved notification sent (%d issue(s))", len(part.Resolved))
+				for _, o := range part.Resolved {
+					delete(st.Open, o.Key)
+				}
+			}
+		}
+		if part.alerting() {
+			if err := s.notifier.Notify(part.alert()); err != nil {
+				log.Printf("Failed to send notification: %v", err)
+				sendErr = err
+				for _, list := range [][]issue{part.New, part.Escalated, part.Reminders} {
+					for _, i := range list {
+						failed[i.Key] = true
+					}
+				}
+			} else {
+				log.Println("Alert notification sent")
+			}
+		}
+	}
+	for key, o := range next.Open {
+		if !failed[key] {
+			st.Open[key] = o
+		}
+	}
+	s.alerts = st
+
+	if statePath != "" {
+		if err := saveAlertState(statePath, s.alerts); err != nil {
+			log.Printf("Alert state: %v", err)
+		}
+	}
+	return sendErr
+}
+
+// renotifyInterval returns how often still-open issues are notified again;
+// zero means never.
+func (s *Service) renotifyInterval() time.Duration {
+	v := s.cfg.Notify.RenotifyInterval
+	if v == "" || v == "0" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("Invalid notify.renotify_interval %q, not re-notifying", v)
+		return 0
+	}
+	return d
+}
+
+// Prune applies the configured retention rules: it destroys every snapshot
+// with the configured prefix that no rule keeps, or with dryRun only logs and
+// reports what it would destroy. The returned report is never nil.
+func (s *Service) Prune(dryRun bool) (*report.PruneReport, error) {
+	pr := &report.PruneReport{DryRun: dryRun, Destroyed: []string{}}
+
+	for _, rule := range s.cfg.Retention.Datasets {
+		if retention.KeepsNothing(rule) {
+			log.Printf("Retention: rule for %s keeps no snapshots, ignoring it", rule.Dataset)
+		}
+	}
+
+	snaps, err := s.zfs.ListSnapshots()
+	if err != nil {
+		pr.Error = err.Error()
+		return pr, err
+	}
+
+	var toDestroy []string
+	for _, d := range retention.Plan(s.cfg.Retention.Datasets, s.cfg.Defaults.SnapshotPrefix, snaps) {
+		if d.Keep {
+			pr.Kept++
+			continue
+		}
+		log.Printf("Retention: pruning %s (%s)", d.Snapshot.Name, d.Reason)
+		toDestroy = append(toDestroy, d.Snapshot.Name)
+	}
+
+	if dryRun {
+		pr.Destroyed = append(pr.Destroyed, toDestroy...)
+		log.Printf("Retention (dry run): would prune %d snapshot(s), keeping %d", len(toDestroy), pr.Kept)
+		return pr, nil
+	}
+
+	results := s.zfs.DestroySnapshots(toDestroy)
+	for _, name := range toDestroy {
+		if err := results[name]; err != nil {
+			log.Printf("Retention: %v", err)
+			pr.Failed = append(pr.Failed, report.PruneFailure{Snapshot: name, Error: err.Error()})
+			continue
+		}
+		pr.Destroyed = append(pr.Destroyed, name)
+	}
+	log.Printf("Retention: pruned %d snapshot(s), kept %d, %d failed",
+		len(pr.Destroyed), pr.Kept, len(pr.Failed))
+	return pr, nil
 }
 
 // Run starts the monitoring loop.
@@ -121,6 +418,12 @@ func (s *Service) Run() error {
 	if s.cfg.Monitor.ReportPath != "" {
 		log.Printf("Health report path: %s", s.cfg.Monitor.ReportPath)
 	}
+	if s.cfg.Monitor.TextfilePath != "" {
+		log.Printf("Metrics textfile path: %s", s.cfg.Monitor.TextfilePath)
+	}
+	if n := len(s.cfg.Retention.Datasets); n > 0 {
+		log.Printf("Retention rules: %d (dry run: %v)", n, s.cfg.Retention.DryRun)
+	}
 
 	if len(s.cfg.Notify.ShoutrrrURLs) > 0 {
 		log.Printf("Configured %d notification service(s)", len(s.cfg.Notify.ShoutrrrURLs))
@@ -128,6 +431,25 @@ func (s *Service) Run() error {
 	if s.cfg.Notify.Desktop {
 		log.Println("Desktop notifications enabled")
 	}
+	if hb := s.cfg.Monitor.Heartbeat; hb.URL != "" || hb.ShoutrrrURL != "" {
+		log.Println("Heartbeat pings enabled")
+	}
+
+	if s.cfg.Monitor.MetricsAddress != "" {
+		s.serveMetrics()
+	}
+	if addr := s.cfg.Monitor.APIAddress; addr != "" {
+		serve("status API", addr, s.APIHandler())
+	}
+
+	if jobs := s.snapshotJobs(time.Now()); len(jobs) > 0 {
+		log.Printf("Snapshot schedules: %d", len(jobs))
+		go s.runSnapshots(jobs, nil)
+	}
+	if jobs := s.scrubJobs(time.Now()); len(jobs) > 0 {
+		log.Printf("Scrub schedules: %d", len(jobs))
+		go s.runScrubs(jobs, nil)
+	}
 
 	// Run immediately on start
 	if err := s.RunOnce(); err != nil {
@@ -0,0 +1,322 @@
+package monitor
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/notify"
+	"github.com/pbek/zfsguard/internal/report"
+	"github.com/pbek/zfsguard/internal/zfs/zfstest"
+)
+
+func newTestService(t *testing.T, scenario string) (*Service, string) {
+	t.Helper()
+	r, err := zfstest.Scenario(scenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Notify.Desktop = false
+	cfg.Monitor.ReportPath = filepath.Join(t.TempDir(), "health-report.json")
+	return New(cfg, r), cfg.Monitor.ReportPath
+}
+
+func TestRunOnceReport(t *testing.T) {
+	tests := []struct {
+		scenario  string
+		poolState string
+		unhealthy []string
+	}{
+		{"healthy", "ONLINE", nil},
+		{"healthy-json", "ONLINE", nil},
+		{"degraded", "DEGRADED", nil},
+		{"smart-failed", "ONLINE", []string{"/dev/sdb"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scenario, func(t *testing.T) {
+			svc, path := newTestService(t, tt.scenario)
+			if err := svc.RunOnce(); err != nil {
+				t.Fatalf("RunOnce: %v", err)
+			}
+
+			r, err := report.Read(path)
+			if err != nil {
+				t.Fatalf("report.Read: %v", err)
+			}
+			if r.PoolError != "" || r.DiskError != "" {
+				t.Fatalf("unexpected check errors: %q / %q", r.PoolError, r.DiskError)
+			}
+			if len(r.Pools) != 1 || r.Pools[0].State != tt.poolState {
+				t.Errorf("pools = %+v, want state %s", r.Pools, tt.poolState)
+			}
+			var unhealthy []string
+			for _, d := range r.Disks {
+				if !d.Healthy {
+					unhealthy = append(unhealthy, d.Device)
+				}
+			}
+			if len(unhealthy) != len(tt.unhealthy) {
+				t.Fatalf("unhealthy disks = %q, want %q", unhealthy, tt.unhealthy)
+			}
+			for i := range unhealthy {
+				if unhealthy[i] != tt.unhealthy[i] {
+					t.Errorf("unhealthy disks = %q, want %q", unhealthy, tt.unhealthy)
+				}
+			}
+		})
+	}
+}
+
+func TestRunOnceCommandsFail(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Notify.Desktop = false
+	cfg.Monitor.ReportPath = filepath.Join(t.TempDir(), "health-report.json")
+
+	// No fixtures: every command fails as if zpool and smartctl were missing.
+	svc := New(cfg, zfstest.New())
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	r, err := report.Read(cfg.Monitor.ReportPath)
+	if err != nil {
+		t.Fatalf("report.Read: %v", err)
+	}
+	if r.PoolError == "" || r.DiskError == "" {
+		t.Errorf("expected pool and disk errors, got %q / %q", r.PoolError, r.DiskError)
+	}
+}
+
+func TestRunOncePrunes(t *testing.T) {
+	r, err := zfstest.Scenario("healthy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Notify.Desktop = false
+	cfg.Monitor.ReportPath = filepath.Join(t.TempDir(), "health-report.json")
+	cfg.Retention.Datasets = []config.RetentionRule{{Dataset: "tank", Recursive: true, Daily: 1}}
+
+	if err := New(cfg, r).RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if !r.Called("zfs destroy tank/data@zfsguard_2026-02-01") {
+		t.Errorf("expired snapshot not pruned: %q", r.Calls())
+	}
+
+	rep, err := report.Read(cfg.Monitor.ReportPath)
+	if err != nil {
+		t.Fatalf("report.Read: %v", err)
+	}
+	p := rep.Pruning
+	if p == nil || len(p.Destroyed) != 1 || p.Destroyed[0] != "tank/data@zfsguard_2026-02-01" {
+		t.Fatalf("pruning = %+v", p)
+	}
+	// tank/data@zfsguard_2026-02-08 and the only tank/home snapshot are kept.
+	if p.Kept != 2 || len(p.Failed) != 0 {
+		t.Errorf("pruning = %+v", p)
+	}
+}
+
+func TestPruneDryRun(t *testing.T) {
+	r, err := zfstest.Scenario("healthy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Retention.Datasets = []config.RetentionRule{{Dataset: "tank/data", Daily: 1}}
+
+	p, err := New(cfg, r).Prune(true)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if !p.DryRun || len(p.Destroyed) != 1 || p.Kept != 1 {
+		t.Errorf("pruning = %+v", p)
+	}
+	for _, c := range r.Calls() {
+		if strings.HasPrefix(c, "zfs destroy") {
+			t.Errorf("dry run ran %q", c)
+		}
+	}
+}
+
+func TestRunOnceReportVdevs(t *testing.T) {
+	svc, path := newTestService(t, "degraded")
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	r, err := report.Read(path)
+	if err != nil {
+		t.Fatalf("report.Read: %v", err)
+	}
+
+	p := r.Pools[0]
+	if len(p.Vdevs) != 1 || p.Vdevs[0].Children[0].Name != "mirror-0" {
+		t.Fatalf("vdevs = %+v", p.Vdevs)
+	}
+	faulted := p.FaultedDevices()
+	if len(faulted) != 1 || faulted[0].Name != "sdb" || faulted[0].State != "FAULTED" {
+		t.Errorf("faulted = %+v", faulted)
+	}
+	if !strings.HasPrefix(p.Scan, "resilvered") || p.Action == "" || p.Status == "" {
+		t.Errorf("scan = %q, action = %q, status = %q", p.Scan, p.Action, p.Status)
+	}
+	if r.Healthy() {
+		t.Error("degraded report is healthy")
+	}
+}
+
+func TestRunOnceReportSMARTAttributes(t *testing.T) {
+	svc, path := newTestService(t, "smart-worn")
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	r, err := report.Read(path)
+	if err != nil {
+		t.Fatalf("report.Read: %v", err)
+	}
+	if len(r.Disks) != 3 {
+		t.Fatalf("disks = %+v, want 3", r.Disks)
+	}
+	for _, d := range r.Disks {
+		if d.Attributes == nil {
+			t.Fatalf("%s: no attributes in report", d.Device)
+		}
+	}
+	if a := r.Disks[0].Attributes; a.Protocol != "ATA" || len(a.ATA) != 7 || a.ATA[1].Raw != 8 {
+		t.Errorf("ATA attributes = %+v", a)
+	}
+	if a := r.Disks[1].Attributes; a.NVMe == nil || a.NVMe.PercentageUsed != 86 {
+		t.Errorf("NVMe attributes = %+v", a)
+	}
+	if a := r.Disks[2].Attributes; a.SCSI == nil || a.SCSI.GrownDefects != 2 {
+		t.Errorf("SCSI attributes = %+v", a)
+	}
+}
+
+func TestRunOnceSMARTRules(t *testing.T) {
+	svc, path := newTestService(t, "smart-worn")
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	r, err := report.Read(path)
+	if err != nil {
+		t.Fatalf("report.Read: %v", err)
+	}
+	want := map[string]struct {
+		health report.Level
+		issues []string
+	}{
+		"/dev/sda": {"critical", []string{
+			"Reallocated_Sector_Ct 8 > 0 (warning)",
+			"Current_Pending_Sector 12 > 10 (critical)",
+			"temperature 58 > 55 (warning)",
+		}},
+		"/dev/nvme0": {"warning", []string{"percentage_used 86 > 80 (warning)"}},
+		"/dev/sdc":   {"warning", []string{"grown_defects 2 > 0 (warning)"}},
+	}
+	for _, d := range r.Disks {
+		w := want[d.Device]
+		if d.Health != w.health || d.Healthy || strings.Join(d.Issues, "|") != strings.Join(w.issues, "|") {
+			t.Errorf("%s: health %q issues %q, want %q %q", d.Device, d.Health, d.Issues, w.health, w.issues)
+		}
+	}
+	if r.Healthy() {
+		t.Error("report with SMART warnings is healthy")
+	}
+}
+
+func TestApplySMARTRulesOverride(t *testing.T) {
+	// Rules replace the defaults; a rule without thresholds never fires.
+	svc, path := newTestService(t, "smart-worn")
+	limit := 100.0
+	svc.cfg.Monitor.SMARTRules = []config.SMARTRule{
+		{Attribute: "temperature", Critical: &limit},
+		{Attribute: "Reallocated_Sector_Ct"},
+	}
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	r, err := report.Read(path)
+	if err != nil {
+		t.Fatalf("report.Read: %v", err)
+	}
+	for _, d := range r.Disks {
+		if d.Health != "ok" || len(d.Issues) != 0 {
+			t.Errorf("%s: health %q issues %q, want ok", d.Device, d.Health, d.Issues)
+		}
+	}
+}
+
+func TestRunOnceAppendsHistory(t *testing.T) {
+	svc, path := newTestService(t, "degraded")
+	for range 2 {
+		if err := svc.RunOnce(); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+	}
+	reports, err := report.History(report.HistoryPath(path), time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("report.History: %v", err)
+	}
+	if len(reports) != 2 || reports[1].Pools[0].State != "DEGRADED" {
+		t.Errorf("history = %+v", reports)
+	}
+
+	svc.cfg.Monitor.HistoryDays = 0
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if reports, _ := report.History(report.HistoryPath(path), time.Time{}, time.Time{}); len(reports) != 2 {
+		t.Errorf("history_days 0 appended: %d reports", len(reports))
+	}
+}
+
+func TestRunOnceCapacityThresholds(t *testing.T) {
+	// The healthy pool is 31% full.
+	svc, path := newTestService(t, "healthy")
+	rec := &recorder{}
+	svc.notifier = rec
+	svc.cfg.Monitor.CapacityWarning = 30
+	svc.cfg.Monitor.CapacityCritical = 50
+
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	r, err := report.Read(path)
+	if err != nil {
+		t.Fatalf("report.Read: %v", err)
+	}
+	p := r.Pools[0]
+	if p.Capacity != 31 || p.Fragmentation != 7 || p.DedupRatio != 1 || p.CapacityHealth != "warning" {
+		t.Errorf("pool = %+v", p)
+	}
+	if p.Healthy() || r.Healthy() {
+		t.Error("pool above the capacity warning is healthy")
+	}
+	want := `ZFS: Pool "tank" is 31% full (warning at 30%)`
+	if len(rec.sent) != 1 || rec.sent[0].Severity != notify.Warning || !strings.Contains(rec.sent[0].Body, want) {
+		t.Fatalf("notifications = %+v, want %q", rec.sent, want)
+	}
+
+	svc.cfg.Monitor.CapacityCritical = 31
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	want = `ZFS: Pool "tank" is 31% full (critical at 31%)`
+	if len(rec.sent) != 2 || rec.sent[1].Severity != notify.Critical || !strings.Contains(rec.sent[1].Body, "Escalated:\n- "+want) {
+		t.Fatalf("notifications = %+v, want escalation %q", rec.sent, want)
+	}
+
+	// With the defaults (80%/90%) the pool is fine again.
+	svc.cfg.Monitor.CapacityWarning, svc.cfg.Monitor.CapacityCritical = 80, 90
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if len(rec.sent) != 3 || rec.sent[2].Title != "ZFSGuard Resolved" {
+		t.Fatalf("notifications = %+v, want resolved", rec.sent)
+	}
+}
@@ -0,0 +1,169 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/notify"
+	"github.com/pbek/zfsguard/internal/schedule"
+	"github.com/pbek/zfsguard/internal/zfs"
+)
+
+// scrubIssues returns the scrub problems of the pools: a last scrub that
+// finished with errors (critical) or had to repair data (warning), and, when
+// maxAge is set, a last scrub older than maxAge or none at all (warning).
+// Pools that are scrubbing or resilvering right now are not checked for age.
+func scrubIssues(pools []zfs.PoolStatus, maxAge time.Duration, now time.Time) []issue {
+	var issues []issue
+	for _, p := range pools {
+		key := "pool:" + p.Name
+		scan := zfs.ParseScan(p.Scan)
+		scrubbed := scan.Function == "scrub" && scan.State == zfs.ScanFinished
+
+		switch {
+		case scrubbed && scan.Errors > 0:
+			issues = append(issues, issue{Key: key + ":scrub", Severity: notify.Critical, Pool: p.Name,
+				Message: fmt.Sprintf("ZFS: Last scrub of pool %q finished with %d errors", p.Name, scan.Errors)})
+		case scrubbed && scan.Repaired > 0:
+			issues = append(issues, issue{Key: key + ":scrub", Severity: notify.Warning, Pool: p.Name,
+				Message: fmt.Sprintf("ZFS: Last scrub of pool %q repaired %s", p.Name, zfs.FormatBytes(scan.Repaired))})
+		}
+
+		if maxAge <= 0 {
+			continue
+		}
+		switch {
+		case scrubbed && !scan.End.IsZero() && now.Sub(scan.End) > maxAge:
+			issues = append(issues, issue{Key: key + ":scrub-age", Severity: notify.Warning, Pool: p.Name,
+				Message: fmt.Sprintf("ZFS: Pool %q was last scrubbed %s ago (on %s)",
+					p.Name, formatDuration(now.Sub(scan.End)), scan.End.Format("2006-01-02"))})
+		case strings.TrimSpace(p.Scan) == "none requested":
+			issues = append(issues, issue{Key: key + ":scrub-age", Severity: notify.Warning, Pool: p.Name,
+				Message: fmt.Sprintf("ZFS: Pool %q has never been scrubbed", p.Name)})
+		}
+	}
+	return issues
+}
+
+// scrubMaxAge returns monitor.scrub_max_age_days as a duration.
+func (s *Service) scrubMaxAge() time.Duration {
+	return time.Duration(s.cfg.Monitor.ScrubMaxAgeDays) * 24 * time.Hour
+}
+
+// scrubJob is a configured scrub schedule with its parsed timing.
+type scrubJob struct {
+	cfg  config.ScrubSchedule
+	when schedule.Schedule
+	next time.Time
+}
+
+// scrubJobs parses the configured scrub schedules. Invalid schedules are
+// logged and skipped.
+func (s *Service) scrubJobs(now time.Time) []*scrubJob {
+	var jobs []*scrubJob
+	for _, sched := range s.cfg.Scrubs.Schedules {
+		if sched.Pool == "" {
+			log.Printf("Scrub schedule: pool is required, skipping")
+			continue
+		}
+		when, err := schedule.Parse(sched.Interval, sched.Cron)
+		if err != nil {
+			log.Printf("Scrub schedule for %s: %v, skipping", sched.Pool, err)
+			continue
+		}
+		job := &scrubJob{cfg: sched, when: when, next: when.Next(now)}
+		if job.next.IsZero() {
+			log.Printf("Scrub schedule for %s never fires, skipping", sched.Pool)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// runScrubs starts the scheduled scrubs until stop is closed. It runs
+// independently of the health check ticker.
+func (s *Service) runScrubs(jobs []*scrubJob, stop <-chan struct{}) {
+	if len(jobs) == 0 {
+		return
+	}
+	for {
+		next := jobs[0].next
+		for _, j := range jobs[1:] {
+			if j.next.Before(next) {
+				next = j.next
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		active := jobs[:0]
+		for _, j := range jobs {
+			if !j.next.After(now) {
+				s.runScrubJob(j)
+				j.next = j.when.Next(now)
+			}
+			if j.next.IsZero() {
+				log.Printf("Scrub schedule for %s never fires again, stopping it", j.cfg.Pool)
+				continue
+			}
+			active = append(active, j)
+		}
+		if jobs = active; len(jobs) == 0 {
+			return
+		}
+	}
+}
+
+// startScrub starts a scrub of pool unless the pool is already scrubbing or
+// resilvering; started is false when it was skipped for that reason.
+func (s *Service) startScrub(pool string) (started bool, err error) {
+	pools, err := s.zfs.PoolStatuses()
+	if err != nil {
+		return false, err
+	}
+	for _, p := range pools {
+		if p.Name != pool {
+			continue
+		}
+		if scan := zfs.ParseScan(p.Scan); scan.Active() {
+			log.Printf("Scrub of %s skipped: %s %s", pool, scan.Function, scan.State)
+			return false, nil
+		}
+		if err := s.zfs.StartScrub(pool); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("pool %q not found", pool)
+}
+
+func (s *Service) runScrubJob(j *scrubJob) {
+	started, err := s.startScrub(j.cfg.Pool)
+	if err != nil {
+		log.Printf("Scheduled scrub failed: %v", err)
+		msg := notify.Message{
+			Title:    "ZFSGuard Alert",
+			Body:     fmt.Sprintf("Scheduled scrub of %s failed: %v\n", j.cfg.Pool, err),
+			Severity: notify.Warning,
+			Pools:    []string{j.cfg.Pool},
+		}
+		if err := s.notifier.Notify(msg); err != nil {
+			log.Printf("Failed to send notification: %v", err)
+		}
+		return
+	}
+	if started {
+		log.Printf("Started scrub of %s", j.cfg.Pool)
+	}
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/notify"
+	"github.com/pbek/zfsguard/internal/zfs"
+	"github.com/pbek/zfsguard/internal/zfs/zfstest"
+)
+
+func TestScrubIssues(t *testing.T) {
+	now := time.Date(2026, 3, 20, 12, 0, 0, 0, time.Local)
+	pools := []zfs.PoolStatus{
+		{Name: "clean", Scan: "scrub repaired 0B in 00:12:31 with 0 errors on Sun Mar 15 00:36:32 2026"},
+		{Name: "old", Scan: "scrub repaired 0B in 00:12:31 with 0 errors on Sun Feb  8 00:36:32 2026"},
+		{Name: "repaired", Scan: "scrub repaired 4K in 00:12:31 with 0 errors on Sun Mar 15 00:36:32 2026"},
+		{Name: "errors", Scan: "scrub repaired 4K in 00:12:31 with 2 errors on Sun Mar 15 00:36:32 2026"},
+		{Name: "never", Scan: "none requested"},
+		{Name: "resilvering", Scan: "resilver in progress since Fri Mar 20 11:00:00 2026\n\t640M resilvered, 51.65% done, no estimated completion time"},
+	}
+
+	got := map[string]issue{}
+	for _, i := range scrubIssues(pools, 30*24*time.Hour, now) {
+		got[i.Key] = i
+	}
+	want := map[string]notify.Severity{
+		"pool:old:scrub-age":   notify.Warning,
+		"pool:repaired:scrub":  notify.Warning,
+		"pool:errors:scrub":    notify.Critical,
+		"pool:never:scrub-age": notify.Warning,
+	}
+	if len(got) != len(want) {
+		t.Errorf("issues = %+v, want keys %v", got, want)
+	}
+	for key, severity := range want {
+		if got[key].Severity != severity {
+			t.Errorf("%s = %+v, want %s", key, got[key], severity)
+		}
+	}
+	if msg := got["pool:old:scrub-age"].Message; msg != `ZFS: Pool "old" was last scrubbed 40d 11h ago (on 2026-02-08)` {
+		t.Errorf("message = %q", msg)
+	}
+	if msg := got["pool:repaired:scrub"].Message; msg != `ZFS: Last scrub of pool "repaired" repaired 4.0K` {
+		t.Errorf("message = %q", msg)
+	}
+
+	if issues := scrubIssues(pools, 0, now); len(issues) != 2 {
+		t.Errorf("without max age: %+v, want only the two scrub results", issues)
+	}
+}
+
+func TestRunOnceScrubAge(t *testing.T) {
+	svc, _ := newTestService(t, "healthy")
+	svc.cfg.Monitor.ScrubMaxAgeDays = 30
+	rec := &recorder{}
+	svc.notifier = rec
+	svc.now = func() time.Time { return time.Date(2026, 3, 20, 12, 0, 0, 0, time.Local) }
+
+	if err := svc.RunOnce(); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if len(rec.sent) != 1 || rec.sent[0].Severity != notify.Warning {
+		t.Fatalf("sent = %+v, want one warning", rec.sent)
+	}
+}
+
+func TestStartScrubSkipsActivePools(t *testing.T) {
+	const status = "  pool: tank\n state: ONLINE\n  scan: %s\nconfig:\n\n\tNAME  STATE  READ WRITE CKSUM\n\ttank  ONLINE    0     0     0\n\nerrors: No known data errors\n"
+	tests := []struct {
+		scan    string
+		started bool
+	}{
+		{"scrub repaired 0B in 00:12:31 with 0 errors on Sun Feb  8 00:36:32 2026", true},
+		{"resilver in progress since Mon Feb  9 10:59:32 2026\n\t640M resilvered, 51.65% done, no estimated completion time", false},
+		{"scrub paused since Mon Feb  9 08:00:00 2026\n\tscrub started on Sun Feb  8 00:24:01 2026", false},
+	}
+	for _, tt := range tests {
+		r := zfstest.New()
+		r.SetRoot(true)
+		r.Set("zpool list -H -o name,health", zfstest.Response{Stdout: "tank\tONLINE\n"})
+		r.Set("zpool status tank", zfstest.Response{Stdout: fmt.Sprintf(status, tt.scan)})
+		r.Set("zpool scrub tank", zfstest.Response{})
+		svc := New(config.DefaultConfig(), r)
+
+		started, err := svc.startScrub("tank")
+		if err != nil || started != tt.started || r.Called("zpool scrub tank") != tt.started {
+			t.Errorf("%s: started = %v, %v; calls %q", tt.scan, started, err, r.Calls())
+		}
+	}
+
+	if _, err := New(config.DefaultConfig(), zfstest.New()).startScrub("tank"); err == nil {
+		t.Error("startScrub of a missing pool succeeded")
+	}
+}
+
+func TestScrubJobsSkipsInvalid(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Scrubs.Schedules = []config.ScrubSchedule{
+		{Pool: "tank", Cron: "0 2 1 * *"},
+		{Interval: "168h"},
+		{Pool: "tank"},
+	}
+	if jobs := New(cfg, zfstest.New()).scrubJobs(time.Now()); len(jobs) != 1 {
+		t.Errorf("got %d jobs, want 1", len(jobs))
+	}
+}
@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/zfs"
+)
+
+// CheckSMART checks the configured disks (or every detected disk) and grades
+// them with the configured threshold rules.
+func (s *Service) CheckSMART() ([]zfs.SMARTStatus, error) {
+	disks, err := s.zfs.CheckSMART(s.cfg.Monitor.SMARTDevices)
+	if err != nil {
+		return nil, err
+	}
+	for i := range disks {
+		ApplySMARTRules(&disks[i], s.cfg.Monitor.SMARTRules)
+	}
+	return disks, nil
+}
+
+// ApplySMARTRules escalates the disk for every rule whose threshold it
+// exceeds, e.g. "Reallocated_Sector_Ct 8 > 0 (warning)". Only the most
+// severe threshold of a rule is reported.
+func ApplySMARTRules(disk *zfs.SMARTStatus, rules []config.SMARTRule) {
+	if disk.Attributes == nil {
+		return
+	}
+	for _, rule := range rules {
+		v, ok := disk.Attributes.Value(rule.Attribute)
+		if !ok {
+			continue
+		}
+		for _, level := range []struct {
+			health    zfs.SMARTHealth
+			threshold *float64
+		}{
+			{zfs.SMARTCritical, rule.Critical},
+			{zfs.SMARTWarning, rule.Warning},
+		} {
+			if level.threshold != nil && v > *level.threshold {
+				disk.Escalate(level.health, fmt.Sprintf("%s %s > %s (%s)",
+					rule.Attribute, formatValue(v), formatValue(*level.threshold), level.health))
+				break
+			}
+		}
+	}
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/notify"
+	"github.com/pbek/zfsguard/internal/schedule"
+)
+
+// snapshotTimeFormat is the timestamp part of scheduled snapshot names; it
+// matches the default name suggested by the TUI.
+const snapshotTimeFormat = "2006-01-02_15-04-05"
+
+// defaultSnapshotLabel is used for schedules without a label.
+const defaultSnapshotLabel = "auto"
+
+// snapshotJob is a configured snapshot schedule with its parsed timing.
+type snapshotJob struct {
+	cfg  config.SnapshotSchedule
+	when schedule.Schedule
+	next time.Time
+}
+
+// SnapshotName returns the name of the snapshot sched takes at t:
+// "<dataset>@<prefix>_<label>_<timestamp>".
+func (s *Service) SnapshotName(sched config.SnapshotSchedule, t time.Time) string {
+	label := sched.Label
+	if label == "" {
+		label = defaultSnapshotLabel
+	}
+	return fmt.Sprintf("%s@%s_%s_%s",
+		sched.Dataset, s.cfg.Defaults.SnapshotPrefix, label, t.Format(snapshotTimeFormat))
+}
+
+// TakeSnapshot creates the snapshot for sched at t and returns its name.
+func (s *Service) TakeSnapshot(sched config.SnapshotSchedule, t time.Time) (string, error) {
+	name := s.SnapshotName(sched, t)
+	if err := s.zfs.CreateSnapshot(name, sched.Recursive); err != nil {
+		return name, err
+	}
+	return name, nil
+}
+
+// snapshotJobs parses the configured schedules. Invalid schedules are
+// logged and skipped.
+func (s *Service) snapshotJobs(now time.Time) []*snapshotJob {
+	var jobs []*snapshotJob
+	for _, sched := range s.cfg.Snapshots.Schedules {
+		if sched.Dataset == "" {
+			log.Printf("Snapshot schedule %q: dataset is required, skipping", sched.Label)
+			continue
+		}
+		when, err := schedule.Parse(sched.Interval, sched.Cron)
+		if err != nil {
+			log.Printf("Snapshot schedule for %s: %v, skipping", sched.Dataset, err)
+			continue
+		}
+		job := &snapshotJob{cfg: sched, when: when, next: when.Next(now)}
+		if job.next.IsZero() {
+			log.Printf("Snapshot schedule for %s never fires, skipping", sched.Dataset)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// runSnapshots takes the scheduled snapshots until stop is closed. It runs
+// independently of the health check ticker.
+func (s *Service) runSnapshots(jobs []*snapshotJob, stop <-chan struct{}) {
+	if len(jobs) == 0 {
+		return
+	}
+	for {
+		next := jobs[0].next
+		for _, j := range jobs[1:] {
+			if j.next.Before(next) {
+				next = j.next
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		active := jobs[:0]
+		for _, j := range jobs {
+			if !j.next.After(now) {
+				s.runSnapshotJob(j, j.next)
+				j.next = j.when.Next(now)
+			}
+			if j.next.IsZero() {
+				log.Printf("Snapshot schedule for %s never fires again, stopping it", j.cfg.Dataset)
+				continue
+			}
+			active = append(active, j)
+		}
+		if jobs = active; len(jobs) == 0 {
+			return
+		}
+	}
+}
+
+func (s *Service) runSnapshotJob(j *snapshotJob, at time.Time) {
+	name, err := s.TakeSnapshot(j.cfg, at)
+	if err != nil {
+		log.Printf("Scheduled snapshot failed: %v", err)
+		msg := notify.Message{
+			Title:    "ZFSGuard Alert",
+			Body:     fmt.Sprintf("Scheduled snapshot %s failed: %v\n", name, err),
+			Severity: notify.Warning,
+			Pools:    []string{poolOf(name)},
+		}
+		if err := s.notifier.Notify(msg); err != nil {
+			log.Printf("Failed to send notification: %v", err)
+		}
+		return
+	}
+	log.Printf("Created snapshot %s", name)
+}
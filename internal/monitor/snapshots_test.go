@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/schedule"
+	"github.com/pbek/zfsguard/internal/zfs/zfstest"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	r := zfstest.New()
+	r.Set("zfs snapshot -r tank@zfsguard_hourly_2026-02-08_10-00-00", zfstest.Response{})
+	r.Set("zfs snapshot tank/data@zfsguard_auto_2026-02-08_10-00-00", zfstest.Response{})
+	svc := New(config.DefaultConfig(), r)
+	at := time.Date(2026, 2, 8, 10, 0, 0, 0, time.Local)
+
+	name, err := svc.TakeSnapshot(config.SnapshotSchedule{Dataset: "tank", Label: "hourly", Recursive: true}, at)
+	if err != nil || name != "tank@zfsguard_hourly_2026-02-08_10-00-00" {
+		t.Errorf("TakeSnapshot = %q, %v", name, err)
+	}
+	name, err = svc.TakeSnapshot(config.SnapshotSchedule{Dataset: "tank/data"}, at)
+	if err != nil || name != "tank/data@zfsguard_auto_2026-02-08_10-00-00" {
+		t.Errorf("TakeSnapshot = %q, %v", name, err)
+	}
+}
+
+func TestSnapshotJobsSkipsInvalid(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Snapshots.Schedules = []config.SnapshotSchedule{
+		{Dataset: "tank", Interval: "1h"},
+		{Dataset: "tank", Cron: "0 * * * *"},
+		{Interval: "1h"},
+		{Dataset: "tank", Interval: "1h", Cron: "0 * * * *"},
+		{Dataset: "tank", Cron: "0 0 31 2 *"},
+	}
+	now := time.Date(2026, 2, 8, 10, 15, 0, 0, time.Local)
+
+	jobs := New(cfg, zfstest.New()).snapshotJobs(now)
+	if len(jobs) != 2 {
+		t.Fatalf("got %d jobs, want 2", len(jobs))
+	}
+	if want := now.Truncate(time.Hour).Add(time.Hour); !jobs[1].next.Equal(want) {
+		t.Errorf("cron job next = %s, want %s", jobs[1].next, want)
+	}
+}
+
+func TestRunSnapshotsTakesDueSnapshots(t *testing.T) {
+	r := zfstest.New()
+	cfg := config.DefaultConfig()
+	cfg.Notify.Desktop = false
+	svc := New(cfg, r)
+
+	due := time.Now().Add(-time.Second)
+	job := &snapshotJob{
+		cfg:  config.SnapshotSchedule{Dataset: "tank", Label: "hourly"},
+		when: schedule.Every(time.Hour),
+		next: due,
+	}
+	want := "zfs snapshot " + svc.SnapshotName(job.cfg, due)
+	r.Set(want, zfstest.Response{})
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		svc.runSnapshots([]*snapshotJob{job}, stop)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !r.Called(want) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(stop)
+	<-done
+
+	if !r.Called(want) {
+		t.Fatalf("calls = %q, want %q", r.Calls(), want)
+	}
+	if !job.next.After(due) {
+		t.Errorf("next = %s, not rescheduled", job.next)
+	}
+}
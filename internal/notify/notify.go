@@ -5,12 +5,55 @@ package notify
 import (
 	"fmt"
 	"os/exec"
+	"path"
+	"strconv"
 	"strings"
 
 	"github.com/containrrr/shoutrrr"
 	"github.com/pbek/zfsguard/internal/config"
 )
 
+// Severity classifies a notification.
+type Severity string
+
+const (
+	Info     Severity = "info"
+	Warning  Severity = "warning"
+	Critical Severity = "critical"
+)
+
+// Rank orders severities: info < warning < critical.
+func (s Severity) Rank() int {
+	switch s {
+	case Info:
+		return 0
+	case Warning:
+		return 1
+	}
+	return 2
+}
+
+// urgency is the notify-send urgency for the severity.
+func (s Severity) urgency() string {
+	switch s {
+	case Info:
+		return "low"
+	case Warning:
+		return "normal"
+	}
+	return "critical"
+}
+
+// Message is a notification. Pools and Devices name what it is about and
+// are matched against the route patterns.
+type Message struct {
+	Title    string
+	Body     string
+	Severity Severity
+	Pools    []string
+	Devices  []string
+}
+
 // Notifier dispatches notifications to configured services.
 type Notifier struct {
 	cfg config.NotifyConfig
@@ -21,14 +64,21 @@ func New(cfg config.NotifyConfig) *Notifier {
 	return &Notifier{cfg: cfg}
 }
 
-// Send sends a notification with the given title and message to all
-// configured notification services.
+// Send sends a critical notification with the given title and message to
+// the services routed for it.
 func (n *Notifier) Send(title, message string) error {
+	return n.Notify(Message{Title: title, Body: message, Severity: Critical})
+}
+
+// Notify sends m to the Shoutrrr URLs of every matching route, or to
+// shoutrrr_urls when no route matches, and to the desktop with an urgency
+// derived from the severity.
+func (n *Notifier) Notify(m Message) error {
 	var errs []string
 
 	// Send to shoutrrr services
-	for _, url := range n.cfg.ShoutrrrURLs {
-		formatted := fmt.Sprintf("[%s] %s", title, message)
+	for _, url := range n.urlsFor(m) {
+		formatted := fmt.Sprintf("[%s] %s", m.Title, m.Body)
 		if err := shoutrrr.Send(url, formatted); err != nil {
 			errs = append(errs, fmt.Sprintf("shoutrrr (%s): %v", maskURL(url), err))
 		}
@@ -36,7 +86,7 @@ func (n *Notifier) Send(title, message string) error {
 
 	// Send desktop notification
 	if n.cfg.Desktop {
-		if err := sendDesktop(title, message); err != nil {
+		if err := sendDesktop(m.Title, m.Body, m.Severity.urgency()); err != nil {
 			errs = append(errs, fmt.Sprintf("desktop: %v", err))
 		}
 	}
@@ -47,11 +97,78 @@ func (n *Notifier) Send(title, message string) error {
 	return nil
 }
 
+// urlsFor returns the Shoutrrr URLs m is routed to, without duplicates.
+func (n *Notifier) urlsFor(m Message) []string {
+	var urls []string
+	seen := map[string]bool{}
+	for _, r := range n.cfg.Routes {
+		if !routeMatches(r, m) {
+			continue
+		}
+		for _, url := range r.URLs {
+			if !seen[url] {
+				seen[url] = true
+				urls = append(urls, url)
+			}
+		}
+	}
+	if len(seen) == 0 {
+		return n.cfg.ShoutrrrURLs
+	}
+	return urls
+}
+
+// RouteKey identifies the routes m matches. Messages with the same key are
+// delivered to the same Shoutrrr URLs; the empty key stands for
+// shoutrrr_urls.
+func (n *Notifier) RouteKey(m Message) string {
+	var matched []string
+	for i, r := range n.cfg.Routes {
+		if routeMatches(r, m) {
+			matched = append(matched, strconv.Itoa(i))
+		}
+	}
+	return strings.Join(matched, ",")
+}
+
+// routeMatches reports whether m has one of the route's severities and, if
+// the route has patterns, a pool and a device matching them.
+func routeMatches(r config.NotifyRoute, m Message) bool {
+	if len(r.Severities) > 0 {
+		ok := false
+		for _, s := range r.Severities {
+			if Severity(s) == m.Severity {
+				ok = true
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return matchesAny(r.Pools, m.Pools) && matchesAny(r.Devices, m.Devices)
+}
+
+// matchesAny reports whether one of names matches one of the glob
+// patterns. No patterns match everything.
+func matchesAny(patterns, names []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		for _, name := range names {
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // sendDesktop sends a local Linux desktop notification using notify-send.
-func sendDesktop(title, message string) error {
+func sendDesktop(title, message, urgency string) error {
 	// Try notify-send first (most common on Linux desktops)
-	if path, err := exec.LookPath("notify-send"); err == nil {
-		cmd := exec.Command(path, "--app-name=zfsguard", "--urgency=critical", title, message)
+	if bin, err := exec.LookPath("notify-send"); err == nil {
+		cmd := exec.Command(bin, "--app-name=zfsguard", "--urgency="+urgency, title, message)
 		if out, err := cmd.CombinedOutput(); err != nil {
 			return fmt.Errorf("notify-send failed: %s: %w", string(out), err)
 		}
@@ -61,6 +178,15 @@ func sendDesktop(title, message string) error {
 	return fmt.Errorf("no desktop notification tool found (install libnotify/notify-send)")
 }
 
+// SendURL sends message to a single Shoutrrr URL, bypassing the routes;
+// used for heartbeats.
+func SendURL(url, message string) error {
+	if err := shoutrrr.Send(url, message); err != nil {
+		return fmt.Errorf("shoutrrr (%s): %w", maskURL(url), err)
+	}
+	return nil
+}
+
 // maskURL masks sensitive parts of notification URLs for error messages.
 func maskURL(url string) string {
 	// Show only the scheme and first few chars
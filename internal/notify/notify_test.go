@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pbek/zfsguard/internal/config"
+)
+
+func TestRoutes(t *testing.T) {
+	n := New(config.NotifyConfig{
+		ShoutrrrURLs: []string{"ntfy://ntfy.sh/default"},
+		Routes: []config.NotifyRoute{
+			{Severities: []string{"critical"}, URLs: []string{"pushover://pager"}},
+			{Pools: []string{"backup*"}, URLs: []string{"ntfy://ntfy.sh/backup"}},
+			{Severities: []string{"warning", "critical"}, Devices: []string{"/dev/nvme*"},
+				URLs: []string{"ntfy://ntfy.sh/ssd", "pushover://pager"}},
+		},
+	})
+	tests := []struct {
+		name string
+		msg  Message
+		want []string
+	}{
+		{"no route matches", Message{Severity: Warning, Pools: []string{"tank"}},
+			[]string{"ntfy://ntfy.sh/default"}},
+		{"severity", Message{Severity: Critical, Pools: []string{"tank"}},
+			[]string{"pushover://pager"}},
+		{"pool pattern", Message{Severity: Info, Pools: []string{"tank", "backup2"}},
+			[]string{"ntfy://ntfy.sh/backup"}},
+		{"several routes without duplicates", Message{Severity: Critical, Devices: []string{"/dev/nvme0"}},
+			[]string{"pushover://pager", "ntfy://ntfy.sh/ssd"}},
+		{"device pattern needs a device", Message{Severity: Warning},
+			[]string{"ntfy://ntfy.sh/default"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.urlsFor(tt.msg); !slices.Equal(got, tt.want) {
+				t.Errorf("urlsFor = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := n.RouteKey(Message{Severity: Critical, Devices: []string{"/dev/nvme0"}}); got != "0,2" {
+		t.Errorf("RouteKey = %q, want %q", got, "0,2")
+	}
+	if got := n.RouteKey(Message{Severity: Warning, Pools: []string{"tank"}}); got != "" {
+		t.Errorf("RouteKey without matching route = %q, want empty", got)
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	if !(Info.Rank() < Warning.Rank() && Warning.Rank() < Critical.Rank()) {
+		t.Error("severities are not ordered info < warning < critical")
+	}
+	for s, want := range map[Severity]string{Info: "low", Warning: "normal", Critical: "critical"} {
+		if got := s.urgency(); got != want {
+			t.Errorf("%s urgency = %q, want %q", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// HistoryFile is the name of the report history, stored in the directory of
+// the health report.
+const HistoryFile = "health-history.jsonl"
+
+// HistoryPath returns the path of the history kept next to the report at
+// reportPath.
+func HistoryPath(reportPath string) string {
+	return filepath.Join(filepath.Dir(reportPath), HistoryFile)
+}
+
+// AppendHistory appends the report as one JSON line to the history at path
+// and drops the reports older than keep; zero keeps everything. The raw
+// command output is left out to keep the history compact. The file is only
+// rewritten, atomically, when old reports have to be dropped.
+func AppendHistory(path string, r HealthReport, keep time.Duration) error {
+	line, err := json.Marshal(compact(r))
+	if err != nil {
+		return fmt.Errorf("failed to marshal health report: %w", err)
+	}
+	line = append(line, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	if keep > 0 {
+		cutoff := r.Timestamp.Add(-keep)
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read report history: %w", err)
+		}
+		if kept, dropped := dropBefore(data, cutoff); dropped {
+			tmp := path + ".tmp"
+			if err := os.WriteFile(tmp, append(kept, line...), 0644); err != nil {
+				return fmt.Errorf("failed to write report history: %w", err)
+			}
+			if err := os.Rename(tmp, path); err != nil {
+				return fmt.Errorf("failed to rename report history: %w", err)
+			}
+			return nil
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open report history: %w", err)
+	}
+	_, err = f.Write(line)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report history: %w", err)
+	}
+	return nil
+}
+
+// compact returns a copy of the report without raw command output.
+func compact(r HealthReport) HealthReport {
+	r.Pools = append([]PoolReport(nil), r.Pools...)
+	for i := range r.Pools {
+		r.Pools[i].Raw = ""
+	}
+	r.Disks = append([]DiskReport(nil), r.Disks...)
+	for i := range r.Disks {
+		r.Disks[i].Raw = ""
+	}
+	return r
+}
+
+// dropBefore returns the history lines from cutoff on and whether any line
+// was dropped. Lines that cannot be parsed, e.g. one cut short by a crash,
+// are dropped too.
+func dropBefore(data []byte, cutoff time.Time) ([]byte, bool) {
+	var kept []byte
+	dropped := false
+	for line := range bytes.Lines(data) {
+		var entry struct {
+			Timestamp time.Time `json:"timestamp"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil || entry.Timestamp.Before(cutoff) {
+			dropped = true
+			continue
+		}
+		kept = append(kept, line...)
+	}
+	return kept, dropped
+}
+
+// History returns the reports of the history at path taken between since
+// and until, oldest first. A zero since or until is unbounded. A missing
+// history is empty.
+func History(path string, since, until time.Time) ([]HealthReport, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report history: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var reports []HealthReport
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var r HealthReport
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// A line cut short by a crash is skipped.
+			continue
+		}
+		if (!since.IsZero() && r.Timestamp.Before(since)) || (!until.IsZero() && r.Timestamp.After(until)) {
+			continue
+		}
+		reports = append(reports, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read report history: %w", err)
+	}
+	sort.SliceStable(reports, func(i, j int) bool { return reports[i].Timestamp.Before(reports[j].Timestamp) })
+	return reports, nil
+}
+
+// StateChange is a change of the state of a pool or the health of a disk
+// between two reports.
+type StateChange struct {
+	Time time.Time `json:"time"`
+	// Kind is "pool" or "disk".
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// From is empty for the first report a pool or disk appears in; To is
+	// "MISSING" when it is no longer reported.
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Changes returns when each pool's state and each disk's health changed
+// across the reports, which must be oldest first. The first report sets the
+// initial states. Reports whose pool or disk check failed are skipped for
+// that kind, since they say nothing about the state.
+func Changes(reports []HealthReport) []StateChange {
+	var changes []StateChange
+	pools := map[string]string{}
+	disks := map[string]string{}
+
+	track := func(at time.Time, kind string, last, now map[string]string) {
+		names := make([]string, 0, len(now))
+		for name := range now {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prev, ok := last[name]; !ok || prev != now[name] {
+				changes = append(changes, StateChange{Time: at, Kind: kind, Name: name, From: prev, To: now[name]})
+				last[name] = now[name]
+			}
+		}
+		var gone []string
+		for name, prev := range last {
+			if _, ok := now[name]; !ok && prev != "MISSING" {
+				gone = append(gone, name)
+			}
+		}
+		sort.Strings(gone)
+		for _, name := range gone {
+			changes = append(changes, StateChange{Time: at, Kind: kind, Name: name, From: last[name], To: "MISSING"})
+			last[name] = "MISSING"
+		}
+	}
+
+	for _, r := range reports {
+		if r.PoolError == "" {
+			now := map[string]string{}
+			for _, p := range r.Pools {
+				now[p.Name] = p.State
+			}
+			track(r.Timestamp, "pool", pools, now)
+		}
+		if r.DiskError == "" {
+			now := map[string]string{}
+			for _, d := range r.Disks {
+				now[d.Device] = string(d.Level())
+			}
+			track(r.Timestamp, "disk", disks, now)
+		}
+	}
+	return changes
+}
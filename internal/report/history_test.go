@@ -0,0 +1,92 @@
+package report_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/report"
+)
+
+func TestHistoryRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), report.HistoryFile)
+	t0 := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	for day := range 5 {
+		r := report.HealthReport{
+			Timestamp: t0.AddDate(0, 0, day),
+			Pools:     []report.PoolReport{{Name: "tank", State: "ONLINE", Raw: "zpool status output"}},
+		}
+		if err := report.AppendHistory(path, r, 48*time.Hour); err != nil {
+			t.Fatalf("AppendHistory: %v", err)
+		}
+	}
+
+	all, err := report.History(path, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	// Days 2, 3 and 4 are within 48h of the last report.
+	if len(all) != 3 || !all[0].Timestamp.Equal(t0.AddDate(0, 0, 2)) {
+		t.Fatalf("history = %+v", all)
+	}
+	if all[0].Pools[0].Raw != "" {
+		t.Errorf("raw output kept in history: %q", all[0].Pools[0].Raw)
+	}
+
+	some, err := report.History(path, t0.AddDate(0, 0, 3), t0.AddDate(0, 0, 3))
+	if err != nil || len(some) != 1 {
+		t.Errorf("History(day 3) = %+v, %v", some, err)
+	}
+
+	// A line cut short by a crash is skipped.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, append(data, `{"timestamp":"2026-02-0`...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := report.History(path, time.Time{}, time.Time{}); err != nil || len(all) != 3 {
+		t.Errorf("history with truncated line = %d reports, %v", len(all), err)
+	}
+
+	if missing, err := report.History(filepath.Join(t.TempDir(), "none"), time.Time{}, time.Time{}); err != nil || missing != nil {
+		t.Errorf("missing history = %+v, %v", missing, err)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	t0 := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return t0.Add(time.Duration(h) * time.Hour) }
+	reports := []report.HealthReport{
+		{Timestamp: at(0),
+			Pools: []report.PoolReport{{Name: "tank", State: "ONLINE"}},
+			Disks: []report.DiskReport{{Device: "/dev/sda", Healthy: true, Health: "ok"}}},
+		{Timestamp: at(1),
+			Pools: []report.PoolReport{{Name: "tank", State: "DEGRADED"}},
+			Disks: []report.DiskReport{{Device: "/dev/sda", Health: "warning"}}},
+		// A failed check says nothing about the state.
+		{Timestamp: at(2), PoolError: "zpool not found",
+			Disks: []report.DiskReport{{Device: "/dev/sda", Health: "warning"}}},
+		{Timestamp: at(3),
+			Pools: []report.PoolReport{{Name: "tank", State: "ONLINE"}}},
+	}
+
+	var got []string
+	for _, c := range report.Changes(reports) {
+		got = append(got, strings.Join([]string{c.Time.Format("15"), c.Kind, c.Name, c.From, c.To}, " "))
+	}
+	want := []string{
+		"00 pool tank  ONLINE",
+		"00 disk /dev/sda  ok",
+		"01 pool tank ONLINE DEGRADED",
+		"01 disk /dev/sda ok warning",
+		"03 pool tank DEGRADED ONLINE",
+		"03 disk /dev/sda warning MISSING",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
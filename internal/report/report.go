@@ -17,6 +17,26 @@ import (
 // DefaultPath is the default location for the health report file.
 const DefaultPath = "/var/lib/zfsguard/health-report.json"
 
+// Level is the graded health of a pool's capacity or of a disk.
+type Level string
+
+const (
+	LevelOK       Level = "ok"
+	LevelWarning  Level = "warning"
+	LevelCritical Level = "critical"
+)
+
+// SMARTLevel returns the level of a disk graded by SMART.
+func SMARTLevel(h zfs.SMARTHealth) Level {
+	switch h {
+	case zfs.SMARTOK:
+		return LevelOK
+	case zfs.SMARTWarning:
+		return LevelWarning
+	}
+	return LevelCritical
+}
+
 // HealthReport is the top-level structure written to disk as JSON.
 type HealthReport struct {
 	Timestamp time.Time    `json:"timestamp"`
@@ -24,22 +44,128 @@ type HealthReport struct {
 	Disks     []DiskReport `json:"disks"`
 	PoolError string       `json:"pool_error,omitempty"`
 	DiskError string       `json:"disk_error,omitempty"`
+	Pruning   *PruneReport `json:"pruning,omitempty"`
 }
 
 // PoolReport mirrors zfs.PoolStatus with JSON tags.
 type PoolReport struct {
-	Name   string `json:"name"`
-	State  string `json:"state"`
-	Errors string `json:"errors"`
-	Raw    string `json:"raw"`
+	Name   string       `json:"name"`
+	State  string       `json:"state"`
+	Errors string       `json:"errors"`
+	Status string       `json:"status,omitempty"`
+	Action string       `json:"action,omitempty"`
+	Scan   string       `json:"scan,omitempty"`
+	Vdevs  []VdevReport `json:"vdevs,omitempty"`
+	// Size, Allocated and Free are in bytes, Capacity and Fragmentation
+	// in percent; all zero when zpool list could not be read.
+	Size          uint64  `json:"size,omitempty"`
+	Allocated     uint64  `json:"allocated,omitempty"`
+	Free          uint64  `json:"free,omitempty"`
+	Capacity      int     `json:"capacity,omitempty"`
+	Fragmentation int     `json:"fragmentation,omitempty"`
+	DedupRatio    float64 `json:"dedup_ratio,omitempty"`
+	// CapacityHealth is "ok", "warning" or "critical" by the configured
+	// capacity thresholds; empty when the capacity is unknown.
+	CapacityHealth Level  `json:"capacity_health,omitempty"`
+	Raw            string `json:"raw"`
+}
+
+// VdevReport mirrors zfs.VdevNode with JSON tags.
+type VdevReport struct {
+	Name     string       `json:"name"`
+	Type     string       `json:"type"`
+	State    string       `json:"state,omitempty"`
+	Read     uint64       `json:"read"`
+	Write    uint64       `json:"write"`
+	Checksum uint64       `json:"checksum"`
+	Message  string       `json:"message,omitempty"`
+	Children []VdevReport `json:"children,omitempty"`
+}
+
+func vdevReports(nodes []zfs.VdevNode) []VdevReport {
+	var out []VdevReport
+	for _, n := range nodes {
+		out = append(out, VdevReport{
+			Name:     n.Name,
+			Type:     n.Type,
+			State:    n.State,
+			Read:     n.Read,
+			Write:    n.Write,
+			Checksum: n.Checksum,
+			Message:  n.Message,
+			Children: vdevReports(n.Children),
+		})
+	}
+	return out
+}
+
+// FaultedDevices returns the devices that are not healthy or have reported
+// errors, leaves only.
+func (p PoolReport) FaultedDevices() []VdevReport {
+	var out []VdevReport
+	var walk func(vdevs []VdevReport)
+	walk = func(vdevs []VdevReport) {
+		for _, v := range vdevs {
+			if len(v.Children) > 0 {
+				walk(v.Children)
+				continue
+			}
+			if v.Type == "disk" && zfs.DeviceNeedsAttention(v.State, v.Read, v.Write, v.Checksum) {
+				out = append(out, v)
+			}
+		}
+	}
+	walk(p.Vdevs)
+	return out
+}
+
+// Healthy reports whether the pool is ONLINE without known data errors or
+// faulted devices and below the capacity thresholds.
+func (p PoolReport) Healthy() bool {
+	return p.State == "ONLINE" && (p.Errors == "" || p.Errors == "No known data errors") &&
+		len(p.FaultedDevices()) == 0 && (p.CapacityHealth == "" || p.CapacityHealth == LevelOK)
 }
 
 // DiskReport mirrors zfs.SMARTStatus with JSON tags.
 type DiskReport struct {
-	Device  string `json:"device"`
-	Healthy bool   `json:"healthy"`
-	Summary string `json:"summary"`
-	Raw     string `json:"raw"`
+	Device string `json:"device"`
+	// Healthy is true when Health is "ok".
+	Healthy bool `json:"healthy"`
+	// Health is "ok", "warning" or "critical"; empty in reports written
+	// before disks were graded.
+	Health     Level            `json:"health,omitempty"`
+	Summary    string           `json:"summary"`
+	Issues     []string         `json:"issues,omitempty"`
+	Attributes *SMARTAttributes `json:"attributes,omitempty"`
+	Raw        string           `json:"raw"`
+}
+
+// Level returns Health, derived from Healthy for older reports.
+func (d DiskReport) Level() Level {
+	switch {
+	case d.Health != "":
+		return d.Health
+	case d.Healthy:
+		return LevelOK
+	}
+	return LevelCritical
+}
+
+// PruneReport records the outcome of the monitor's retention pass.
+type PruneReport struct {
+	DryRun bool `json:"dry_run,omitempty"`
+	// Destroyed lists the pruned snapshots (in a dry run: the snapshots
+	// that would have been pruned).
+	Destroyed []string       `json:"destroyed"`
+	Kept      int            `json:"kept"`
+	Failed    []PruneFailure `json:"failed,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// PruneFailure is a snapshot the retention pass failed to destroy.
+type PruneFailure struct {
+	Snapshot string `json:"snapshot"`
+	Error    string `json:"error"`
 }
 
 // FromChecks builds a HealthReport from raw ZFS and SMART check results.
@@ -61,6 +187,10 @@ func FromChecks(
 			Name:   p.Name,
 			State:  p.State,
 			Errors: p.Errors,
+			Status: p.Status,
+			Action: p.Action,
+			Scan:   p.Scan,
+			Vdevs:  vdevReports(p.Config),
 			Raw:    p.Raw,
 		})
 	}
@@ -70,16 +200,99 @@ func FromChecks(
 	}
 	for _, d := range disks {
 		r.Disks = append(r.Disks, DiskReport{
-			Device:  d.Device,
-			Healthy: d.Healthy,
-			Summary: d.Summary,
-			Raw:     d.Raw,
+			Device:     d.Device,
+			Healthy:    d.Healthy(),
+			Health:     SMARTLevel(d.Health),
+			Summary:    d.Summary,
+			Issues:     d.Issues,
+			Attributes: smartAttributes(d.Attributes),
+			Raw:        d.Raw,
 		})
 	}
 
 	return r
 }
 
+// SetUsage adds the space accounting from zpool list to the pools.
+func (r *HealthReport) SetUsage(usages []zfs.PoolUsage) {
+	for i := range r.Pools {
+		for _, u := range usages {
+			if u.Name == r.Pools[i].Name {
+				r.Pools[i].Size = u.Size
+				r.Pools[i].Allocated = u.Allocated
+				r.Pools[i].Free = u.Free
+				r.Pools[i].Capacity = u.Capacity
+				r.Pools[i].Fragmentation = u.Fragmentation
+				r.Pools[i].DedupRatio = u.DedupRatio
+			}
+		}
+	}
+}
+
+// Healthy reports whether the report shows no problem: no check failed,
+// every pool is healthy (see PoolReport.Healthy) and every disk is healthy.
+func (r HealthReport) Healthy() bool {
+	if r.PoolError != "" || r.DiskError != "" {
+		return false
+	}
+	for _, p := range r.Pools {
+		if !p.Healthy() {
+			return false
+		}
+	}
+	for _, d := range r.Disks {
+		if !d.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// Problems lists why the report is unhealthy, e.g. "pool tank is DEGRADED"
+// or "disk /dev/sdb is critical"; it is empty for a healthy report.
+func (r HealthReport) Problems() []string {
+	var out []string
+	if r.PoolError != "" {
+		out = append(out, "ZFS check failed: "+r.PoolError)
+	}
+	if r.DiskError != "" {
+		out = append(out, "SMART check failed: "+r.DiskError)
+	}
+	for _, p := range r.Pools {
+		switch {
+		case p.Healthy():
+		case p.State != "ONLINE":
+			out = append(out, fmt.Sprintf("pool %s is %s", p.Name, p.State))
+		case p.CapacityHealth != "" && p.CapacityHealth != LevelOK:
+			out = append(out, fmt.Sprintf("pool %s is %d%% full", p.Name, p.Capacity))
+		default:
+			out = append(out, fmt.Sprintf("pool %s has errors or faulted devices", p.Name))
+		}
+	}
+	for _, d := range r.Disks {
+		if level := d.Level(); level != LevelOK {
+			out = append(out, fmt.Sprintf("disk %s is %s", d.Device, level))
+		}
+	}
+	return out
+}
+
+// StaleAfter returns the age after which the report of a monitor checking
+// every intervalMinutes is stale: two intervals, so one slow or skipped
+// cycle is tolerated. An interval of 0 or less is the monitor's default of
+// 60 minutes.
+func StaleAfter(intervalMinutes int) time.Duration {
+	if intervalMinutes <= 0 {
+		intervalMinutes = 60
+	}
+	return 2 * time.Duration(intervalMinutes) * time.Minute
+}
+
+// Stale reports whether the report is older than maxAge at now.
+func (r HealthReport) Stale(now time.Time, maxAge time.Duration) bool {
+	return now.Sub(r.Timestamp) > maxAge
+}
+
 // Write atomically writes the report as JSON to the given path.
 // It writes to a temporary file first and renames to avoid partial reads.
 func Write(path string, r HealthReport) error {
@@ -0,0 +1,74 @@
+package report_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/report"
+	"github.com/pbek/zfsguard/internal/zfs"
+)
+
+func TestStale(t *testing.T) {
+	if got := report.StaleAfter(15); got != 30*time.Minute {
+		t.Errorf("StaleAfter(15) = %s", got)
+	}
+	if got := report.StaleAfter(0); got != 2*time.Hour {
+		t.Errorf("StaleAfter(0) = %s", got)
+	}
+
+	now := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	r := report.HealthReport{Timestamp: now.Add(-time.Hour)}
+	if r.Stale(now, 2*time.Hour) {
+		t.Error("report of one hour ago is stale after two hours")
+	}
+	if !r.Stale(now, 30*time.Minute) {
+		t.Error("report of one hour ago is not stale after 30 minutes")
+	}
+}
+
+func TestProblems(t *testing.T) {
+	r := report.HealthReport{
+		DiskError: "smartctl not found",
+		Pools: []report.PoolReport{
+			{Name: "ok", State: "ONLINE", CapacityHealth: "ok"},
+			{Name: "deg", State: "DEGRADED"},
+			{Name: "full", State: "ONLINE", Capacity: 93, CapacityHealth: "critical"},
+			{Name: "errs", State: "ONLINE", Errors: "1 data errors, use '-v' for a list"},
+		},
+		Disks: []report.DiskReport{{Device: "/dev/sda", Healthy: true}, {Device: "/dev/sdb", Health: "warning"}},
+	}
+	want := []string{
+		"SMART check failed: smartctl not found",
+		"pool deg is DEGRADED",
+		"pool full is 93% full",
+		"pool errs has errors or faulted devices",
+		"disk /dev/sdb is warning",
+	}
+	if got := r.Problems(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Problems =\n%q\nwant\n%q", got, want)
+	}
+	if got := (report.HealthReport{}).Problems(); got != nil {
+		t.Errorf("Problems of an empty report = %q", got)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	for h, want := range map[zfs.SMARTHealth]report.Level{
+		zfs.SMARTOK:       report.LevelOK,
+		zfs.SMARTWarning:  report.LevelWarning,
+		zfs.SMARTCritical: report.LevelCritical,
+	} {
+		if got := report.SMARTLevel(h); got != want {
+			t.Errorf("SMARTLevel(%q) = %q, want %q", h, got, want)
+		}
+	}
+
+	// Reports written before disks were graded only have Healthy.
+	if got := (report.DiskReport{Healthy: true}).Level(); got != report.LevelOK {
+		t.Errorf("Level of a healthy disk = %q", got)
+	}
+	if got := (report.DiskReport{}).Level(); got != report.LevelCritical {
+		t.Errorf("Level of an unhealthy disk = %q", got)
+	}
+}
@@ -0,0 +1,71 @@
+package report
+
+import "github.com/pbek/zfsguard/internal/zfs"
+
+// SMARTAttributes mirrors zfs.SMARTAttributes with JSON tags.
+type SMARTAttributes struct {
+	Protocol     string             `json:"protocol"`
+	Model        string             `json:"model,omitempty"`
+	Serial       string             `json:"serial,omitempty"`
+	Temperature  int                `json:"temperature,omitempty"`
+	PowerOnHours uint64             `json:"power_on_hours,omitempty"`
+	ATA          []ATAAttribute     `json:"ata,omitempty"`
+	NVMe         *NVMeHealth        `json:"nvme,omitempty"`
+	SCSI         *SCSIErrorCounters `json:"scsi,omitempty"`
+}
+
+// ATAAttribute mirrors zfs.ATAAttribute with JSON tags.
+type ATAAttribute struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Value      int    `json:"value"`
+	Worst      int    `json:"worst"`
+	Threshold  int    `json:"threshold"`
+	WhenFailed string `json:"when_failed,omitempty"`
+	Raw        uint64 `json:"raw"`
+	RawString  string `json:"raw_string"`
+}
+
+// NVMeHealth mirrors zfs.NVMeHealth with JSON tags.
+type NVMeHealth struct {
+	CriticalWarning         int    `json:"critical_warning"`
+	AvailableSpare          int    `json:"available_spare"`
+	AvailableSpareThreshold int    `json:"available_spare_threshold"`
+	PercentageUsed          int    `json:"percentage_used"`
+	MediaErrors             uint64 `json:"media_errors"`
+	ErrorLogEntries         uint64 `json:"error_log_entries"`
+	UnsafeShutdowns         uint64 `json:"unsafe_shutdowns"`
+}
+
+// SCSIErrorCounters mirrors zfs.SCSIErrorCounters with JSON tags.
+type SCSIErrorCounters struct {
+	ReadUncorrected   uint64 `json:"read_uncorrected"`
+	WriteUncorrected  uint64 `json:"write_uncorrected"`
+	VerifyUncorrected uint64 `json:"verify_uncorrected"`
+	GrownDefects      uint64 `json:"grown_defects"`
+}
+
+func smartAttributes(a *zfs.SMARTAttributes) *SMARTAttributes {
+	if a == nil {
+		return nil
+	}
+	out := &SMARTAttributes{
+		Protocol:     a.Protocol,
+		Model:        a.Model,
+		Serial:       a.Serial,
+		Temperature:  a.Temperature,
+		PowerOnHours: a.PowerOnHours,
+	}
+	for _, attr := range a.ATA {
+		out.ATA = append(out.ATA, ATAAttribute(attr))
+	}
+	if a.NVMe != nil {
+		nvme := NVMeHealth(*a.NVMe)
+		out.NVMe = &nvme
+	}
+	if a.SCSI != nil {
+		scsi := SCSIErrorCounters(*a.SCSI)
+		out.SCSI = &scsi
+	}
+	return out
+}
@@ -0,0 +1,140 @@
+// Package retention decides which snapshots to keep and which to prune
+// according to the retention rules in the zfsguard configuration.
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/zfs"
+)
+
+// Decision is the verdict for a single snapshot.
+type Decision struct {
+	Snapshot zfs.Snapshot
+	Keep     bool
+	// Reason explains the verdict, e.g. "daily 2026-02-08", "held" or
+	// "expired".
+	Reason string
+}
+
+// period is one of the retention buckets of a rule.
+type period struct {
+	name  string
+	count int
+	key   func(t time.Time) string
+}
+
+func periods(rule config.RetentionRule) []period {
+	return []period{
+		{"hourly", rule.Hourly, func(t time.Time) string { return t.Format("2006-01-02 15h") }},
+		{"daily", rule.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{"weekly", rule.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{"monthly", rule.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+		{"yearly", rule.Yearly, func(t time.Time) string { return t.Format("2006") }},
+	}
+}
+
+// KeepsNothing reports whether a rule has no positive count. Such a rule
+// would prune every matching snapshot, so Plan ignores it.
+func KeepsNothing(rule config.RetentionRule) bool {
+	return rule.Hourly <= 0 && rule.Daily <= 0 && rule.Weekly <= 0 &&
+		rule.Monthly <= 0 && rule.Yearly <= 0
+}
+
+// RuleFor returns the rule that applies to dataset: an exact match wins,
+// otherwise the closest recursive rule of an ancestor.
+func RuleFor(rules []config.RetentionRule, dataset string) (config.RetentionRule, bool) {
+	var best config.RetentionRule
+	found := false
+	for _, r := range rules {
+		if r.Dataset == dataset {
+			return r, true
+		}
+		if r.Recursive && strings.HasPrefix(dataset, r.Dataset+"/") &&
+			(!found || len(r.Dataset) > len(best.Dataset)) {
+			best = r
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Plan applies the rules to snaps and returns a decision for every snapshot
+// named "<prefix>_..." of a dataset covered by a rule, newest first per
+// dataset. Other snapshots are never touched. Held snapshots and snapshots
+// with clones are kept, as zfs would refuse to destroy them.
+func Plan(rules []config.RetentionRule, prefix string, snaps []zfs.Snapshot) []Decision {
+	byDataset := make(map[string][]zfs.Snapshot)
+	var datasets []string
+	for _, s := range snaps {
+		if !strings.HasPrefix(s.ShortName, prefix+"_") {
+			continue
+		}
+		if _, ok := byDataset[s.Dataset]; !ok {
+			datasets = append(datasets, s.Dataset)
+		}
+		byDataset[s.Dataset] = append(byDataset[s.Dataset], s)
+	}
+	sort.Strings(datasets)
+
+	var decisions []Decision
+	for _, dataset := range datasets {
+		rule, ok := RuleFor(rules, dataset)
+		if !ok || KeepsNothing(rule) {
+			continue
+		}
+		decisions = append(decisions, planDataset(rule, byDataset[dataset])...)
+	}
+	return decisions
+}
+
+func planDataset(rule config.RetentionRule, snaps []zfs.Snapshot) []Decision {
+	sorted := append([]zfs.Snapshot(nil), snaps...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Creation.After(sorted[j].Creation)
+	})
+
+	reasons := make([][]string, len(sorted))
+	for _, p := range periods(rule) {
+		if p.count <= 0 {
+			continue
+		}
+		seen := make(map[string]bool)
+		for i, s := range sorted {
+			if len(seen) >= p.count {
+				break
+			}
+			key := p.key(s.Creation.Local())
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			reasons[i] = append(reasons[i], p.name+" "+key)
+		}
+	}
+
+	decisions := make([]Decision, 0, len(sorted))
+	for i, s := range sorted {
+		d := Decision{Snapshot: s, Keep: true}
+		switch {
+		case len(reasons[i]) > 0:
+			d.Reason = strings.Join(reasons[i], ", ")
+		case s.UserRefs > 0:
+			d.Reason = "held"
+		case len(s.Clones) > 0:
+			d.Reason = "has clones"
+		default:
+			d.Keep = false
+			d.Reason = "expired"
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions
+}
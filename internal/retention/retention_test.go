@@ -0,0 +1,106 @@
+package retention
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/zfs"
+)
+
+func snap(name string, created time.Time) zfs.Snapshot {
+	dataset, short, _ := strings.Cut(name, "@")
+	return zfs.Snapshot{Name: name, Dataset: dataset, ShortName: short, Creation: created}
+}
+
+func kept(decisions []Decision) map[string]string {
+	m := make(map[string]string)
+	for _, d := range decisions {
+		if d.Keep {
+			m[d.Snapshot.Name] = d.Reason
+		}
+	}
+	return m
+}
+
+func TestPlanDailyWeekly(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2026, 2, d, h, 0, 0, 0, time.Local) }
+	snaps := []zfs.Snapshot{
+		snap("tank/data@zfsguard_a", day(1, 0)),  // Sunday, ISO week 5
+		snap("tank/data@zfsguard_b", day(7, 0)),  // Saturday, week 6
+		snap("tank/data@zfsguard_c", day(8, 0)),  // Sunday, week 6
+		snap("tank/data@zfsguard_d", day(9, 0)),  // Monday, week 7
+		snap("tank/data@zfsguard_e", day(9, 12)), // same day, newer
+		snap("tank/data@manual", day(1, 0)),      // other prefix: untouched
+	}
+	rules := []config.RetentionRule{{Dataset: "tank/data", Daily: 2, Weekly: 3}}
+
+	decisions := Plan(rules, "zfsguard", snaps)
+	if len(decisions) != 5 {
+		t.Fatalf("got %d decisions, want 5", len(decisions))
+	}
+	got := kept(decisions)
+	want := map[string]string{
+		"tank/data@zfsguard_e": "daily 2026-02-09, weekly 2026-W07",
+		"tank/data@zfsguard_c": "daily 2026-02-08, weekly 2026-W06",
+		"tank/data@zfsguard_a": "weekly 2026-W05",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("kept = %v, want %v", got, want)
+	}
+	for name, reason := range want {
+		if got[name] != reason {
+			t.Errorf("%s: reason = %q, want %q", name, got[name], reason)
+		}
+	}
+}
+
+func TestPlanKeepsHeldAndCloned(t *testing.T) {
+	now := time.Date(2026, 2, 9, 0, 0, 0, 0, time.Local)
+	held := snap("tank/data@zfsguard_old", now.AddDate(0, 0, -2))
+	held.UserRefs = 1
+	cloned := snap("tank/data@zfsguard_older", now.AddDate(0, 0, -3))
+	cloned.Clones = []string{"tank/restore"}
+	snaps := []zfs.Snapshot{cloned, held, snap("tank/data@zfsguard_new", now)}
+
+	got := kept(Plan([]config.RetentionRule{{Dataset: "tank/data", Daily: 1}}, "zfsguard", snaps))
+	if got["tank/data@zfsguard_old"] != "held" || got["tank/data@zfsguard_older"] != "has clones" {
+		t.Errorf("kept = %v", got)
+	}
+}
+
+func TestRuleFor(t *testing.T) {
+	rules := []config.RetentionRule{
+		{Dataset: "tank", Recursive: true, Daily: 1},
+		{Dataset: "tank/home", Recursive: true, Daily: 2},
+		{Dataset: "tank/home/alice", Daily: 3},
+		{Dataset: "backup", Daily: 4},
+	}
+	tests := []struct {
+		dataset string
+		daily   int
+		ok      bool
+	}{
+		{"tank", 1, true},
+		{"tank/data", 1, true},
+		{"tank/home/bob", 2, true},
+		{"tank/home/alice", 3, true},
+		{"backup", 4, true},
+		{"backup/child", 0, false},
+		{"tankette", 0, false},
+	}
+	for _, tt := range tests {
+		rule, ok := RuleFor(rules, tt.dataset)
+		if ok != tt.ok || rule.Daily != tt.daily {
+			t.Errorf("RuleFor(%q) = %+v, %v; want daily %d, %v", tt.dataset, rule, ok, tt.daily, tt.ok)
+		}
+	}
+}
+
+func TestPlanIgnoresEmptyRule(t *testing.T) {
+	snaps := []zfs.Snapshot{snap("tank/data@zfsguard_a", time.Now())}
+	if d := Plan([]config.RetentionRule{{Dataset: "tank/data"}}, "zfsguard", snaps); len(d) != 0 {
+		t.Errorf("decisions = %+v, want none", d)
+	}
+}
@@ -0,0 +1,100 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func at(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		spec, from, want string
+	}{
+		{"* * * * *", "2026-02-08 10:15", "2026-02-08 10:16"},
+		{"0 * * * *", "2026-02-08 10:00", "2026-02-08 11:00"},
+		{"*/15 * * * *", "2026-02-08 10:16", "2026-02-08 10:30"},
+		{"30 2 * * *", "2026-02-08 10:16", "2026-02-09 02:30"},
+		{"0 0 1 * *", "2026-02-08 10:16", "2026-03-01 00:00"},
+		{"0 0 * * 0", "2026-02-09 00:00", "2026-02-15 00:00"}, // Sunday
+		{"0 0 * * 7", "2026-02-09 00:00", "2026-02-15 00:00"},
+		{"0 0 13 * 5", "2026-02-09 00:00", "2026-02-13 00:00"}, // Friday or the 13th
+		{"0 9-17/4 * * 1-5", "2026-02-06 18:00", "2026-02-09 09:00"},
+		{"0 0 1,15 6 *", "2026-02-08 10:16", "2026-06-01 00:00"},
+		{"0 0 29 2 *", "2026-02-08 10:16", "2028-02-29 00:00"},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.spec, err)
+		}
+		if got := c.Next(at(tt.from)); !got.Equal(at(tt.want)) {
+			t.Errorf("%q after %s = %s, want %s", tt.spec, tt.from, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+}
+
+func TestCronNever(t *testing.T) {
+	c, err := ParseCron("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Next(at("2026-02-08 10:16")); !got.IsZero() {
+		t.Errorf("Next = %s, want zero", got)
+	}
+}
+
+func TestEveryNext(t *testing.T) {
+	tests := []struct {
+		every      time.Duration
+		from, want string
+	}{
+		{time.Hour, "2026-02-08 10:00", "2026-02-08 11:00"},
+		{7 * time.Minute, "2026-02-08 10:15", "2026-02-08 10:22"},
+		{90 * time.Minute, "2026-02-08 10:15", "2026-02-08 10:30"},
+		{24 * time.Hour, "2026-02-08 10:15", "2026-02-09 00:00"},
+	}
+	for _, tt := range tests {
+		if got := Every(tt.every).Next(at(tt.from)); !got.Equal(at(tt.want)) {
+			t.Errorf("%s after %s = %s, want %s", tt.every, tt.from, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+
+	// Alignment is in UTC, whatever the location of t.
+	berlin := time.FixedZone("CET", 3600)
+	got := Every(24 * time.Hour).Next(at("2026-02-08 10:15").In(berlin))
+	if !got.Equal(at("2026-02-09 00:00")) || got.Location() != berlin {
+		t.Errorf("24h in CET = %s", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse("1h", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Next(at("2026-02-08 10:15")); !got.Equal(at("2026-02-08 11:00")) {
+		t.Errorf("1h after 10:15 = %s", got)
+	}
+
+	for _, tt := range []struct{ every, cron string }{
+		{"", ""},
+		{"1h", "0 * * * *"},
+		{"10s", ""},
+		{"soon", ""},
+		{"", "* * * *"},
+		{"", "60 * * * *"},
+		{"", "*/0 * * * *"},
+		{"", "5-1 * * * *"},
+	} {
+		if _, err := Parse(tt.every, tt.cron); err == nil {
+			t.Errorf("Parse(%q, %q) succeeded", tt.every, tt.cron)
+		}
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/monitor"
 	"github.com/pbek/zfsguard/internal/report"
 	"github.com/pbek/zfsguard/internal/zfs"
 )
@@ -24,6 +26,8 @@ const (
 	viewConfirmDeleteAll
 	viewStatus
 	viewHealth
+	viewConfirmRollback
+	viewClone
 )
 
 // keyMap defines the keybindings for the TUI.
@@ -35,6 +39,10 @@ type keyMap struct {
 	Delete     key.Binding
 	DeleteAll  key.Binding
 	Create     key.Binding
+	Rollback   key.Binding
+	Clone      key.Binding
+	Hold       key.Binding
+	Release    key.Binding
 	Refresh    key.Binding
 	Confirm    key.Binding
 	Cancel     key.Binding
@@ -44,6 +52,10 @@ type keyMap struct {
 	PageDown   key.Binding
 	FilterMode key.Binding
 	Health     key.Binding
+	Scrub      key.Binding
+	PauseScrub key.Binding
+	StopScrub  key.Binding
+	Live       key.Binding
 }
 
 var keys = keyMap{
@@ -54,6 +66,10 @@ var keys = keyMap{
 	Delete:     key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete selected")),
 	DeleteAll:  key.NewBinding(key.WithKeys("D"), key.WithHelp("D", "delete ALL snapshots")),
 	Create:     key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "create snapshot")),
+	Rollback:   key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "rollback to snapshot")),
+	Clone:      key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "clone snapshot")),
+	Hold:       key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "hold (protect)")),
+	Release:    key.NewBinding(key.WithKeys("P"), key.WithHelp("P", "release hold")),
 	Refresh:    key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
 	Confirm:    key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "confirm")),
 	Cancel:     key.NewBinding(key.WithKeys("n", "esc", "escape"), key.WithHelp("n/esc", "cancel")),
@@ -63,6 +79,10 @@ var keys = keyMap{
 	PageDown:   key.NewBinding(key.WithKeys("pgdown", "ctrl+d"), key.WithHelp("PgDn", "page down")),
 	FilterMode: key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter")),
 	Health:     key.NewBinding(key.WithKeys("h"), key.WithHelp("h", "health report")),
+	Scrub:      key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "start/resume scrub (health)")),
+	PauseScrub: key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "pause scrub (health)")),
+	StopScrub:  key.NewBinding(key.WithKeys("X"), key.WithHelp("X", "cancel scrub (health)")),
+	Live:       key.NewBinding(key.WithKeys("l"), key.WithHelp("l", "live checks (health)")),
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
@@ -72,16 +92,28 @@ func (k keyMap) ShortHelp() []key.Binding {
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.PageUp, k.PageDown},
-		{k.Select, k.SelectAll, k.FilterMode},
-		{k.Create, k.Delete, k.DeleteAll, k.Refresh},
-		{k.Health, k.Help, k.Quit},
+		{k.Select, k.SelectAll, k.FilterMode, k.Hold, k.Release},
+		{k.Create, k.Delete, k.DeleteAll, k.Rollback, k.Clone, k.Refresh},
+		{k.Health, k.Live, k.Scrub, k.PauseScrub, k.StopScrub, k.Help, k.Quit},
 	}
 }
 
+// holdTag is the tag used for holds placed and released from the TUI.
+const holdTag = "zfsguard"
+
+// scanRefreshInterval is how often the health view refreshes the scrub and
+// resilver progress of the pools.
+const scanRefreshInterval = 5 * time.Second
+
+// liveHealthInterval is how often the health view re-runs the checks in
+// live mode.
+const liveHealthInterval = 30 * time.Second
+
 // Model is the main TUI model.
 type Model struct {
 	snapshots []zfs.Snapshot
 	datasets  []string
+	holds     map[string][]string // snapshot name -> hold tags
 	cursor    int
 	offset    int // scroll offset for viewport
 	height    int // terminal height
@@ -105,11 +137,51 @@ type Model struct {
 	filterText   string
 	filtered     []int // indices into snapshots that match the filter
 
+	// Clone snapshot form
+	cloneSource     string
+	cloneTarget     textinput.Model
+	cloneMountpoint textinput.Model
+	cloneReadonly   bool
+	cloneFocus      int // 0 = target, 1 = mountpoint, 2 = readonly
+
+	// Delete confirmation
+	estimateFor     string // names the estimate was requested for
+	estimateBytes   uint64 // space reclaimed by the pending delete
+	estimateLoading bool
+	estimateErr     error
+
+	// Rollback confirmation
+	rollbackTarget  string             // snapshot to roll back to
+	rollbackNewer   []zfs.RollbackItem // newer snapshots/bookmarks that would be destroyed
+	rollbackLoading bool
+	rollbackErr     error
+
 	// Health report
 	healthReport  *report.HealthReport
 	healthLoading bool
 	healthScroll  int    // scroll offset for health view
 	reportPath    string // path to the health report file
+	healthPool    int    // selected pool in the health view
+
+	// healthLive shows the result of running the checks directly instead
+	// of the monitor's report. healthGen identifies the current load and
+	// refresh loop; results of older ones are dropped.
+	healthLive bool
+	healthGen  int
+	monitor    *monitor.Service
+	// healthPicked is set once the source was chosen with 'l'; a missing
+	// report then no longer switches to live mode.
+	healthPicked bool
+	// staleAfter is the age at which the monitor's report is stale.
+	staleAfter time.Duration
+
+	// Live scrub/resilver state by pool ("scan:" text of zpool status),
+	// refreshed while the health view is open. scanGen identifies the
+	// current refresh loop; messages of older loops are dropped.
+	scans   map[string]string
+	scanGen int
+
+	zfs *zfs.Client
 
 	err error
 }
@@ -118,6 +190,7 @@ type Model struct {
 type snapshotsLoadedMsg struct {
 	snapshots []zfs.Snapshot
 	datasets  []string
+	holds     map[string][]string
 }
 type errMsg struct{ err error }
 type statusMsg struct {
@@ -126,13 +199,49 @@ type statusMsg struct {
 }
 type clearStatusMsg struct{}
 
+type rollbackPreviewMsg struct {
+	snapshot string
+	newer    []zfs.RollbackItem
+	err      error
+}
+
+type destroyEstimateMsg struct {
+	key   string
+	bytes uint64
+	err   error
+}
+
 type healthLoadedMsg struct {
 	report *report.HealthReport
 	err    error
+	live   bool
+	gen    int
+}
+
+type healthTickMsg struct{ gen int }
+
+type scansLoadedMsg struct {
+	gen   int
+	scans map[string]string
+	err   error
+}
+
+type scanTickMsg struct{ gen int }
+
+// scrubActionMsg is the outcome of starting, pausing or canceling a scrub,
+// together with the scan state read right after it.
+type scrubActionMsg struct {
+	gen   int
+	msg   string
+	err   error
+	scans map[string]string
 }
 
-// NewModel creates a new TUI model.
-func NewModel(reportPath string) Model {
+// NewModel creates a new TUI model. The health view reads the report at
+// cfg.Monitor.ReportPath or runs the checks configured in cfg.Monitor. All
+// zfs commands are executed through runner; a nil runner uses the local
+// system.
+func NewModel(cfg config.Config, runner zfs.Runner) Model {
 	ti := textinput.New()
 	ti.Placeholder = "snapshot-name"
 	ti.CharLimit = 128
@@ -143,40 +252,134 @@ func NewModel(reportPath string) Model {
 	fi.CharLimit = 128
 	fi.Width = 40
 
+	cti := textinput.New()
+	cti.Placeholder = "pool/dataset"
+	cti.CharLimit = 256
+	cti.Width = 40
+
+	cmi := textinput.New()
+	cmi.Placeholder = "inherit"
+	cmi.CharLimit = 256
+	cmi.Width = 40
+
 	return Model{
-		keys:        keys,
-		help:        help.New(),
-		createInput: ti,
-		filterInput: fi,
-		height:      24,
-		width:       80,
-		reportPath:  reportPath,
+		keys:            keys,
+		help:            help.New(),
+		createInput:     ti,
+		filterInput:     fi,
+		cloneTarget:     cti,
+		cloneMountpoint: cmi,
+		height:          24,
+		width:           80,
+		reportPath:      cfg.Monitor.ReportPath,
+		zfs:             zfs.NewClient(runner),
+		monitor:         monitor.New(cfg, runner),
+		staleAfter:      report.StaleAfter(cfg.Monitor.IntervalMinutes),
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return loadSnapshots
+	return loadSnapshots(m.zfs)
 }
 
-func loadSnapshots() tea.Msg {
-	snaps, err := zfs.ListSnapshots()
-	if err != nil {
-		return errMsg{err}
+func loadSnapshots(z *zfs.Client) tea.Cmd {
+	return func() tea.Msg {
+		snaps, err := z.ListSnapshots()
+		if err != nil {
+			return errMsg{err}
+		}
+		datasets, _ := z.ListDatasets()
+
+		var held []string
+		for _, s := range snaps {
+			if s.UserRefs > 0 {
+				held = append(held, s.Name)
+			}
+		}
+		holds := make(map[string][]string, len(held))
+		if list, err := z.Holds(held...); err == nil {
+			for _, h := range list {
+				holds[h.Snapshot] = append(holds[h.Snapshot], h.Tag)
+			}
+		}
+		return snapshotsLoadedMsg{snapshots: snaps, datasets: datasets, holds: holds}
+	}
+}
+
+func loadRollbackPreview(z *zfs.Client, snapshot string) tea.Cmd {
+	return func() tea.Msg {
+		newer, err := z.RollbackPreview(snapshot)
+		return rollbackPreviewMsg{snapshot: snapshot, newer: newer, err: err}
 	}
-	datasets, _ := zfs.ListDatasets()
-	return snapshotsLoadedMsg{snapshots: snaps, datasets: datasets}
 }
 
-func loadHealthFromPath(path string) tea.Cmd {
+func loadDestroyEstimate(z *zfs.Client, key string, names []string) tea.Cmd {
+	return func() tea.Msg {
+		bytes, err := z.EstimateDestroy(names)
+		return destroyEstimateMsg{key: key, bytes: bytes, err: err}
+	}
+}
+
+func loadHealthFromPath(path string, gen int) tea.Cmd {
 	return func() tea.Msg {
 		r, err := report.Read(path)
 		if err != nil {
-			return healthLoadedMsg{err: err}
+			return healthLoadedMsg{err: err, gen: gen}
 		}
-		return healthLoadedMsg{report: &r}
+		return healthLoadedMsg{report: &r, gen: gen}
+	}
+}
+
+func loadLiveHealth(svc *monitor.Service, gen int) tea.Cmd {
+	return func() tea.Msg {
+		r := svc.LiveReport()
+		return healthLoadedMsg{report: &r, live: true, gen: gen}
+	}
+}
+
+func healthTick(gen int) tea.Cmd {
+	return tea.Tick(liveHealthInterval, func(time.Time) tea.Msg {
+		return healthTickMsg{gen: gen}
+	})
+}
+
+// reloadHealth loads the health report from the current source and
+// restarts the live refresh loop.
+func (m *Model) reloadHealth() tea.Cmd {
+	m.healthLoading = true
+	m.healthGen++
+	if m.healthLive {
+		return loadLiveHealth(m.monitor, m.healthGen)
+	}
+	return loadHealthFromPath(m.reportPath, m.healthGen)
+}
+
+// readScans returns the "scan:" text of every pool.
+func readScans(z *zfs.Client) (map[string]string, error) {
+	pools, err := z.PoolStatuses()
+	if err != nil {
+		return nil, err
+	}
+	scans := make(map[string]string, len(pools))
+	for _, p := range pools {
+		scans[p.Name] = p.Scan
+	}
+	return scans, nil
+}
+
+func loadScans(z *zfs.Client, gen int) tea.Cmd {
+	return func() tea.Msg {
+		scans, err := readScans(z)
+		return scansLoadedMsg{gen: gen, scans: scans, err: err}
 	}
 }
 
+func scanTick(gen int) tea.Cmd {
+	return tea.Tick(scanRefreshInterval, func(time.Time) tea.Msg {
+		return scanTickMsg{gen: gen}
+	})
+}
+
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
@@ -188,6 +391,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case snapshotsLoadedMsg:
 		m.snapshots = msg.snapshots
 		m.datasets = msg.datasets
+		m.holds = msg.holds
 		m.err = nil
 		m.applyFilter()
 		m.clampCursor()
@@ -209,9 +413,42 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.statusErr = false
 		return m, nil
 
+	case rollbackPreviewMsg:
+		if msg.snapshot != m.rollbackTarget {
+			return m, nil
+		}
+		m.rollbackLoading = false
+		m.rollbackNewer = msg.newer
+		m.rollbackErr = msg.err
+		return m, nil
+
+	case destroyEstimateMsg:
+		if msg.key != m.estimateFor {
+			return m, nil
+		}
+		m.estimateLoading = false
+		m.estimateBytes = msg.bytes
+		m.estimateErr = msg.err
+		return m, nil
+
 	case healthLoadedMsg:
+		if msg.gen != m.healthGen || msg.live != m.healthLive {
+			return m, nil
+		}
 		m.healthLoading = false
 		m.healthReport = msg.report
+		if msg.live {
+			if m.currentView != viewHealth {
+				return m, nil
+			}
+			return m, healthTick(m.healthGen)
+		}
+		if msg.err != nil && !m.healthPicked {
+			// Without a monitor report, show the checks live instead;
+			// without root they report SMART as unavailable.
+			m.healthLive = true
+			return m, m.reloadHealth()
+		}
 		if msg.err != nil {
 			m.statusMsg = fmt.Sprintf("Health report: %v", msg.err)
 			m.statusErr = true
@@ -221,6 +458,40 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case healthTickMsg:
+		if msg.gen != m.healthGen || m.currentView != viewHealth || !m.healthLive {
+			return m, nil
+		}
+		return m, loadLiveHealth(m.monitor, m.healthGen)
+
+	case scanTickMsg:
+		if msg.gen != m.scanGen || m.currentView != viewHealth {
+			return m, nil
+		}
+		return m, loadScans(m.zfs, m.scanGen)
+
+	case scansLoadedMsg:
+		if msg.gen != m.scanGen || m.currentView != viewHealth {
+			return m, nil
+		}
+		if msg.err == nil {
+			m.scans = msg.scans
+		}
+		return m, scanTick(m.scanGen)
+
+	case scrubActionMsg:
+		if msg.gen == m.scanGen && msg.scans != nil {
+			m.scans = msg.scans
+		}
+		m.statusMsg = msg.msg
+		m.statusErr = msg.err != nil
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("%s: %v", msg.msg, msg.err)
+		}
+		return m, tea.Tick(4*time.Second, func(time.Time) tea.Msg {
+			return clearStatusMsg{}
+		})
+
 	case tea.KeyMsg:
 		return m.handleKey(msg)
 	}
@@ -231,6 +502,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.createInput, cmd = m.createInput.Update(msg)
 		return m, cmd
 	}
+	if m.currentView == viewClone {
+		return m, m.updateCloneInput(msg)
+	}
 	if m.filterActive {
 		var cmd tea.Cmd
 		m.filterInput, cmd = m.filterInput.Update(msg)
@@ -290,6 +564,30 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	// Handle clone view input
+	if m.currentView == viewClone {
+		switch {
+		case key.Matches(msg, key.NewBinding(key.WithKeys("enter"))):
+			return m.executeClone()
+		case key.Matches(msg, key.NewBinding(key.WithKeys("esc", "escape"))):
+			m.currentView = viewList
+			m.cloneTarget.Blur()
+			m.cloneMountpoint.Blur()
+			return m, nil
+		case key.Matches(msg, key.NewBinding(key.WithKeys("tab"))):
+			return m, m.setCloneFocus((m.cloneFocus + 1) % 3)
+		case key.Matches(msg, key.NewBinding(key.WithKeys("shift+tab"))):
+			return m, m.setCloneFocus((m.cloneFocus + 2) % 3)
+		case m.cloneFocus == 2:
+			if key.Matches(msg, key.NewBinding(key.WithKeys(" ", "x"))) {
+				m.cloneReadonly = !m.cloneReadonly
+			}
+			return m, nil
+		default:
+			return m, m.updateCloneInput(msg)
+		}
+	}
+
 	// Handle confirm delete
 	if m.currentView == viewConfirmDelete || m.currentView == viewConfirmDeleteAll {
 		switch {
@@ -302,11 +600,28 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// Handle confirm rollback
+	if m.currentView == viewConfirmRollback {
+		switch {
+		case key.Matches(msg, keys.Confirm):
+			if m.rollbackLoading || m.rollbackErr != nil || len(m.rollbackBlockers()) > 0 {
+				return m, nil
+			}
+			return m.executeRollback()
+		case key.Matches(msg, keys.Cancel):
+			m.currentView = viewList
+			return m, nil
+		}
+		return m, nil
+	}
+
 	// Handle health view
 	if m.currentView == viewHealth {
 		switch {
 		case key.Matches(msg, keys.Cancel), key.Matches(msg, key.NewBinding(key.WithKeys("h"))):
 			m.currentView = viewList
+			m.scanGen++
+			m.healthGen++
 			return m, nil
 		case key.Matches(msg, keys.Quit):
 			return m, tea.Quit
@@ -328,8 +643,27 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.healthScroll += m.viewportHeight()
 			return m, nil
 		case key.Matches(msg, keys.Refresh):
-			m.healthLoading = true
-			return m, loadHealthFromPath(m.reportPath)
+			return m, m.reloadHealth()
+		case key.Matches(msg, keys.Live):
+			m.healthLive = !m.healthLive
+			m.healthPicked = true
+			return m, m.reloadHealth()
+		case key.Matches(msg, key.NewBinding(key.WithKeys("tab"))):
+			if n := m.healthPoolCount(); n > 0 {
+				m.healthPool = (m.healthPool + 1) % n
+			}
+			return m, nil
+		case key.Matches(msg, key.NewBinding(key.WithKeys("shift+tab"))):
+			if n := m.healthPoolCount(); n > 0 {
+				m.healthPool = (m.healthPool - 1 + n) % n
+			}
+			return m, nil
+		case key.Matches(msg, keys.Scrub):
+			return m, m.scrubAction(m.zfs.StartScrub, "Started scrub of %s", "Failed to scrub %s")
+		case key.Matches(msg, keys.PauseScrub):
+			return m, m.scrubAction(m.zfs.PauseScrub, "Paused scrub of %s", "Failed to pause scrub of %s")
+		case key.Matches(msg, keys.StopScrub):
+			return m, m.scrubAction(m.zfs.CancelScrub, "Canceled scrub of %s", "Failed to cancel scrub of %s")
 		case key.Matches(msg, keys.Help):
 			m.showHelp = !m.showHelp
 			return m, nil
@@ -402,28 +736,96 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 		}
 		m.currentView = viewConfirmDelete
-		return m, nil
+		return m, m.startDestroyEstimate()
 
 	case key.Matches(msg, keys.DeleteAll):
 		if len(m.snapshots) == 0 {
 			return m, nil
 		}
 		m.currentView = viewConfirmDeleteAll
-		return m, nil
+		return m, m.startDestroyEstimate()
+
+	case key.Matches(msg, keys.Rollback):
+		idx := m.currentIndex()
+		if idx < 0 {
+			return m, nil
+		}
+		m.currentView = viewConfirmRollback
+		m.rollbackTarget = m.snapshots[idx].Name
+		m.rollbackNewer = nil
+		m.rollbackErr = nil
+		m.rollbackLoading = true
+		return m, loadRollbackPreview(m.zfs, m.rollbackTarget)
+
+	case key.Matches(msg, keys.Clone):
+		idx := m.currentIndex()
+		if idx < 0 {
+			return m, nil
+		}
+		snap := m.snapshots[idx]
+		m.currentView = viewClone
+		m.cloneSource = snap.Name
+		m.cloneTarget.SetValue(suggestCloneTarget(snap))
+		m.cloneMountpoint.SetValue("")
+		m.cloneReadonly = true
+		return m, m.setCloneFocus(0)
+
+	case key.Matches(msg, keys.Hold):
+		return m.executeHold(false)
+
+	case key.Matches(msg, keys.Release):
+		return m.executeHold(true)
 
 	case key.Matches(msg, keys.Refresh):
-		return m, loadSnapshots
+		return m, loadSnapshots(m.zfs)
 
 	case key.Matches(msg, keys.Health):
 		m.currentView = viewHealth
-		m.healthLoading = true
 		m.healthScroll = 0
-		return m, loadHealthFromPath(m.reportPath)
+		m.healthPicked = false
+		m.scanGen++
+		m.scans = nil
+		return m, tea.Batch(m.reloadHealth(), loadScans(m.zfs, m.scanGen))
 	}
 
 	return m, nil
 }
 
+// healthPoolCount returns the number of pools in the health report.
+func (m Model) healthPoolCount() int {
+	if m.healthReport == nil {
+		return 0
+	}
+	return len(m.healthReport.Pools)
+}
+
+// selectedHealthPool returns the name of the pool selected in the health
+// view, or "" if there is none.
+func (m Model) selectedHealthPool() string {
+	n := m.healthPoolCount()
+	if n == 0 {
+		return ""
+	}
+	return m.healthReport.Pools[min(m.healthPool, n-1)].Name
+}
+
+// scrubAction runs action on the selected pool and reads the scan state
+// right after it, so the progress shown follows the change immediately.
+func (m *Model) scrubAction(action func(pool string) error, done, failed string) tea.Cmd {
+	pool := m.selectedHealthPool()
+	if pool == "" {
+		return nil
+	}
+	z, gen := m.zfs, m.scanGen
+	return func() tea.Msg {
+		if err := action(pool); err != nil {
+			return scrubActionMsg{gen: gen, msg: fmt.Sprintf(failed, pool), err: err}
+		}
+		scans, _ := readScans(z)
+		return scrubActionMsg{gen: gen, msg: fmt.Sprintf(done, pool), scans: scans}
+	}
+}
+
 func (m *Model) executeCreate() (tea.Model, tea.Cmd) {
 	name := strings.TrimSpace(m.createInput.Value())
 	if name == "" {
@@ -446,33 +848,84 @@ func (m *Model) executeCreate() (tea.Model, tea.Cmd) {
 	m.currentView = viewList
 	m.createInput.Blur()
 
+	z := m.zfs
 	createCmd := func() tea.Msg {
-		if err := zfs.CreateSnapshot(fullName); err != nil {
+		if err := z.CreateSnapshot(fullName, false); err != nil {
 			return statusMsg{msg: fmt.Sprintf("Failed to create: %v", err), isErr: true}
 		}
 		return statusMsg{msg: fmt.Sprintf("Created snapshot: %s", fullName), isErr: false}
 	}
 
-	return m, tea.Batch(createCmd, loadSnapshots)
+	return m, tea.Batch(createCmd, loadSnapshots(m.zfs))
 }
 
-func (m *Model) executeDelete() (tea.Model, tea.Cmd) {
-	var toDelete []string
+// skippedSnapshot is a snapshot excluded from a delete, with the reason.
+type skippedSnapshot struct {
+	name   string
+	reason string
+}
 
+// deleteTargets splits the snapshots a confirmed delete would act on into
+// those to destroy and those skipped because they are held.
+func (m Model) deleteTargets() ([]string, []skippedSnapshot) {
+	var candidates []zfs.Snapshot
 	if m.currentView == viewConfirmDeleteAll {
-		for i := range m.snapshots {
-			toDelete = append(toDelete, m.snapshots[i].Name)
-		}
+		candidates = m.snapshots
 	} else {
-		for _, s := range m.selectedSnapshots() {
-			toDelete = append(toDelete, s.Name)
+		candidates = m.selectedSnapshots()
+	}
+
+	var toDelete []string
+	var skipped []skippedSnapshot
+	for _, s := range candidates {
+		if s.UserRefs > 0 {
+			reason := "held"
+			if tags := m.holds[s.Name]; len(tags) > 0 {
+				reason = "held by " + strings.Join(tags, ", ")
+			}
+			skipped = append(skipped, skippedSnapshot{name: s.Name, reason: reason})
+			continue
+		}
+		toDelete = append(toDelete, s.Name)
+	}
+	return toDelete, skipped
+}
+
+// skippedSummary formats skipped snapshots for the status bar.
+func skippedSummary(skipped []skippedSnapshot) string {
+	var parts []string
+	for i, s := range skipped {
+		if i >= 3 {
+			parts = append(parts, fmt.Sprintf("and %d more", len(skipped)-3))
+			break
 		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", s.name, s.reason))
 	}
+	return fmt.Sprintf("skipped %d: %s", len(skipped), strings.Join(parts, ", "))
+}
+
+// startDestroyEstimate asks zfs how much space the pending delete frees.
+func (m *Model) startDestroyEstimate() tea.Cmd {
+	toDelete, _ := m.deleteTargets()
+	m.estimateFor = strings.Join(toDelete, "\n")
+	m.estimateBytes = 0
+	m.estimateErr = nil
+	if len(toDelete) == 0 {
+		m.estimateLoading = false
+		return nil
+	}
+	m.estimateLoading = true
+	return loadDestroyEstimate(m.zfs, m.estimateFor, toDelete)
+}
+
+func (m *Model) executeDelete() (tea.Model, tea.Cmd) {
+	toDelete, skipped := m.deleteTargets()
 
 	m.currentView = viewList
 
+	z := m.zfs
 	deleteCmd := func() tea.Msg {
-		errs := zfs.DestroySnapshots(toDelete)
+		errs := z.DestroySnapshots(toDelete)
 		permDenied := false
 		otherErrs := 0
 		for _, err := range errs {
@@ -485,22 +938,210 @@ func (m *Model) executeDelete() (tea.Model, tea.Cmd) {
 			}
 			otherErrs++
 		}
+		suffix := ""
+		if len(skipped) > 0 {
+			suffix = "; " + skippedSummary(skipped)
+		}
 		if permDenied {
 			return statusMsg{
-				msg:   "Delete failed: permission denied; run zfsguard with sudo or configure NOPASSWD",
+				msg:   "Delete failed: permission denied; run zfsguard with sudo or configure NOPASSWD" + suffix,
 				isErr: true,
 			}
 		}
 		if otherErrs > 0 {
 			return statusMsg{
-				msg:   fmt.Sprintf("Delete failed for %d snapshot(s)", otherErrs),
+				msg:   fmt.Sprintf("Delete failed for %d snapshot(s)%s", otherErrs, suffix),
 				isErr: true,
 			}
 		}
-		return statusMsg{msg: fmt.Sprintf("Deleted %d snapshot(s)", len(toDelete)), isErr: false}
+		return statusMsg{
+			msg:   fmt.Sprintf("Deleted %d snapshot(s)%s", len(toDelete), suffix),
+			isErr: false,
+		}
+	}
+
+	return m, tea.Batch(deleteCmd, loadSnapshots(m.zfs))
+}
+
+// executeHold places (or, with release set, removes) the zfsguard hold on the
+// selected snapshots, or on the snapshot under the cursor if none is selected.
+func (m *Model) executeHold(release bool) (tea.Model, tea.Cmd) {
+	var targets []string
+	selected := m.selectedSnapshots()
+	if len(selected) == 0 {
+		if idx := m.currentIndex(); idx >= 0 {
+			selected = []zfs.Snapshot{m.snapshots[idx]}
+		}
+	}
+	for _, s := range selected {
+		has := false
+		for _, tag := range m.holds[s.Name] {
+			if tag == holdTag {
+				has = true
+				break
+			}
+		}
+		// Only act where it changes something, so zfs does not reject the
+		// whole batch because of one snapshot.
+		if has == release {
+			targets = append(targets, s.Name)
+		}
+	}
+	if len(targets) == 0 {
+		if release {
+			m.statusMsg = fmt.Sprintf("No %q hold to release", holdTag)
+		} else {
+			m.statusMsg = fmt.Sprintf("Already held by %q", holdTag)
+		}
+		m.statusErr = true
+		return m, nil
+	}
+
+	z := m.zfs
+	holdCmd := func() tea.Msg {
+		if release {
+			if err := z.Release(holdTag, targets...); err != nil {
+				return statusMsg{msg: fmt.Sprintf("Release failed: %v", err), isErr: true}
+			}
+			return statusMsg{msg: fmt.Sprintf("Released hold on %d snapshot(s)", len(targets))}
+		}
+		if err := z.Hold(holdTag, targets...); err != nil {
+			return statusMsg{msg: fmt.Sprintf("Hold failed: %v", err), isErr: true}
+		}
+		return statusMsg{msg: fmt.Sprintf("Held %d snapshot(s)", len(targets))}
+	}
+
+	return m, tea.Sequence(holdCmd, loadSnapshots(m.zfs))
+}
+
+// suggestCloneTarget proposes a dataset name for a clone of snap in the same
+// pool, e.g. "tank/data@daily" becomes "tank/data-clone-daily". Clones of a
+// pool's root dataset are placed below it ("tank@daily" becomes
+// "tank/tank-clone-daily"), as a sibling would be a new pool.
+func suggestCloneTarget(snap zfs.Snapshot) string {
+	name := snap.Dataset + "-clone-" + snap.ShortName
+	if !strings.Contains(snap.Dataset, "/") {
+		return snap.Dataset + "/" + name
+	}
+	return name
+}
+
+// poolName returns the pool part of a dataset or snapshot name.
+func poolName(name string) string {
+	name, _, _ = strings.Cut(name, "@")
+	pool, _, _ := strings.Cut(name, "/")
+	return pool
+}
+
+func (m *Model) setCloneFocus(focus int) tea.Cmd {
+	m.cloneFocus = focus
+	m.cloneTarget.Blur()
+	m.cloneMountpoint.Blur()
+	switch focus {
+	case 0:
+		m.cloneTarget.Focus()
+	case 1:
+		m.cloneMountpoint.Focus()
+	default:
+		return nil
+	}
+	return textinput.Blink
+}
+
+func (m *Model) updateCloneInput(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	switch m.cloneFocus {
+	case 0:
+		m.cloneTarget, cmd = m.cloneTarget.Update(msg)
+	case 1:
+		m.cloneMountpoint, cmd = m.cloneMountpoint.Update(msg)
+	}
+	return cmd
+}
+
+// cloneProps returns the properties set on the new clone.
+func (m Model) cloneProps() map[string]string {
+	props := map[string]string{}
+	if mp := strings.TrimSpace(m.cloneMountpoint.Value()); mp != "" {
+		props["mountpoint"] = mp
+	}
+	if m.cloneReadonly {
+		props["readonly"] = "on"
+	}
+	return props
+}
+
+func (m *Model) executeClone() (tea.Model, tea.Cmd) {
+	target := strings.TrimSpace(m.cloneTarget.Value())
+	if target == "" {
+		m.statusMsg = "Target dataset cannot be empty"
+		m.statusErr = true
+		return m, nil
+	}
+	if strings.Contains(target, "@") {
+		m.statusMsg = "Target must be a dataset name, not a snapshot"
+		m.statusErr = true
+		return m, nil
+	}
+	if poolName(target) != poolName(m.cloneSource) || !strings.Contains(target, "/") {
+		m.statusMsg = fmt.Sprintf("Clone must be created inside pool %q", poolName(m.cloneSource))
+		m.statusErr = true
+		return m, nil
+	}
+
+	source := m.cloneSource
+	props := m.cloneProps()
+	m.currentView = viewList
+	m.cloneTarget.Blur()
+	m.cloneMountpoint.Blur()
+
+	z := m.zfs
+	cloneCmd := func() tea.Msg {
+		if err := z.CloneSnapshot(source, target, props); err != nil {
+			return statusMsg{msg: fmt.Sprintf("Clone failed: %v", err), isErr: true}
+		}
+		return statusMsg{msg: fmt.Sprintf("Cloned %s to %s", source, target), isErr: false}
+	}
+
+	return m, tea.Sequence(cloneCmd, loadSnapshots(m.zfs))
+}
+
+// rollbackBlockers returns the newer snapshots that have clones. zfs rollback
+// -r cannot destroy them, so the rollback is refused until the clones are
+// promoted or destroyed.
+func (m Model) rollbackBlockers() []zfs.RollbackItem {
+	var blockers []zfs.RollbackItem
+	for _, item := range m.rollbackNewer {
+		if len(item.Clones) > 0 {
+			blockers = append(blockers, item)
+		}
+	}
+	return blockers
+}
+
+func (m *Model) executeRollback() (tea.Model, tea.Cmd) {
+	target := m.rollbackTarget
+	// Newer snapshots or bookmarks require -r, which the dialog has listed.
+	recursive := len(m.rollbackNewer) > 0
+	destroyed := len(m.rollbackNewer)
+
+	m.currentView = viewList
+
+	z := m.zfs
+	rollbackCmd := func() tea.Msg {
+		if err := z.RollbackSnapshot(target, recursive); err != nil {
+			return statusMsg{msg: fmt.Sprintf("Rollback failed: %v", err), isErr: true}
+		}
+		if destroyed > 0 {
+			return statusMsg{
+				msg:   fmt.Sprintf("Rolled back to %s (destroyed %d newer)", target, destroyed),
+				isErr: false,
+			}
+		}
+		return statusMsg{msg: fmt.Sprintf("Rolled back to %s", target), isErr: false}
 	}
 
-	return m, tea.Batch(deleteCmd, loadSnapshots)
+	return m, tea.Sequence(rollbackCmd, loadSnapshots(m.zfs))
 }
 
 func (m *Model) selectedSnapshots() []zfs.Snapshot {
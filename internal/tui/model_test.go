@@ -0,0 +1,470 @@
+package tui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pbek/zfsguard/internal/config"
+	"github.com/pbek/zfsguard/internal/report"
+	"github.com/pbek/zfsguard/internal/zfs"
+	"github.com/pbek/zfsguard/internal/zfs/zfstest"
+)
+
+func newTestModel(t *testing.T, scenario string) (Model, *zfstest.Runner) {
+	t.Helper()
+	r, err := zfstest.Scenario(scenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Monitor.ReportPath = ""
+	m := NewModel(cfg, r)
+	msg := m.Init()()
+	updated, _ := m.Update(msg)
+	return updated.(Model), r
+}
+
+func keyPress(k string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+// update applies msg and returns the resulting model by value.
+func update(t *testing.T, m tea.Model, msg tea.Msg) (Model, tea.Cmd) {
+	t.Helper()
+	updated, cmd := m.Update(msg)
+	switch v := updated.(type) {
+	case Model:
+		return v, cmd
+	case *Model:
+		return *v, cmd
+	}
+	t.Fatalf("unexpected model type %T", updated)
+	return Model{}, nil
+}
+
+// runCmd executes cmd and, recursively, every command of a batch or
+// sequence it returns. The resulting messages are discarded.
+func runCmd(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		for _, c := range batch {
+			runCmd(c)
+		}
+		return
+	}
+	// tea.Sequence returns an unexported []tea.Cmd type.
+	if v := reflect.ValueOf(msg); v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			if c, ok := v.Index(i).Interface().(tea.Cmd); ok {
+				runCmd(c)
+			}
+		}
+	}
+}
+
+func TestRollbackDialogListsNewerSnapshots(t *testing.T) {
+	m, r := newTestModel(t, "healthy")
+
+	m, cmd := update(t, m, keyPress("R"))
+	if m.currentView != viewConfirmRollback || !m.rollbackLoading {
+		t.Fatalf("view = %v, loading = %v", m.currentView, m.rollbackLoading)
+	}
+	m, _ = update(t, m, cmd())
+
+	view := m.View()
+	for _, want := range []string{"tank/data@zfsguard_2026-02-08", "tank/data#replication"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("dialog does not list %s:\n%s", want, view)
+		}
+	}
+
+	m, cmd = update(t, m, keyPress("y"))
+	if m.currentView != viewList {
+		t.Fatalf("view = %v after confirm", m.currentView)
+	}
+	runCmd(cmd)
+	if !r.Called("zfs rollback -r tank/data@zfsguard_2026-02-01") {
+		t.Errorf("rollback -r not executed: %q", r.Calls())
+	}
+}
+
+func TestRollbackDialogBlocksClonedSnapshots(t *testing.T) {
+	m, r := newTestModel(t, "healthy")
+
+	m, _ = update(t, m, keyPress("R"))
+	m, _ = update(t, m, rollbackPreviewMsg{
+		snapshot: m.rollbackTarget,
+		newer: []zfs.RollbackItem{
+			{Name: "tank/data@zfsguard_2026-02-08", Clones: []string{"tank/restore"}},
+		},
+	})
+
+	if view := m.View(); !strings.Contains(view, "Cannot roll back") ||
+		!strings.Contains(view, "tank/restore") {
+		t.Errorf("dialog does not explain the clone conflict:\n%s", view)
+	}
+
+	r.Reset()
+	m, cmd := update(t, m, keyPress("y"))
+	if m.currentView != viewConfirmRollback || cmd != nil {
+		t.Error("confirm was accepted although newer snapshots have clones")
+	}
+}
+
+func TestSuggestCloneTarget(t *testing.T) {
+	tests := []struct {
+		snap zfs.Snapshot
+		want string
+	}{
+		{zfs.Snapshot{Dataset: "tank/data", ShortName: "daily"}, "tank/data-clone-daily"},
+		{zfs.Snapshot{Dataset: "tank", ShortName: "daily"}, "tank/tank-clone-daily"},
+	}
+	for _, tt := range tests {
+		if got := suggestCloneTarget(tt.snap); got != tt.want {
+			t.Errorf("suggestCloneTarget(%s@%s) = %q, want %q",
+				tt.snap.Dataset, tt.snap.ShortName, got, tt.want)
+		}
+	}
+}
+
+func TestCloneForm(t *testing.T) {
+	m, r := newTestModel(t, "healthy")
+
+	m, _ = update(t, m, keyPress("C"))
+	if m.currentView != viewClone {
+		t.Fatalf("view = %v, want clone form", m.currentView)
+	}
+	if got := m.cloneTarget.Value(); got != "tank/data-clone-zfsguard_2026-02-01" {
+		t.Errorf("suggested target = %q", got)
+	}
+
+	m, cmd := update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.currentView != viewList {
+		t.Fatalf("view = %v after enter", m.currentView)
+	}
+	runCmd(cmd)
+	if !r.Called("zfs clone -o readonly=on tank/data@zfsguard_2026-02-01 " +
+		"tank/data-clone-zfsguard_2026-02-01") {
+		t.Errorf("clone not executed: %q", r.Calls())
+	}
+}
+
+func TestCloneFormRejectsOtherPool(t *testing.T) {
+	m, r := newTestModel(t, "healthy")
+
+	m, _ = update(t, m, keyPress("C"))
+	for _, target := range []string{"backup/restore", "tank"} {
+		m.cloneTarget.SetValue(target)
+		r.Reset()
+		m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+		if m.currentView != viewClone || !m.statusErr {
+			t.Errorf("target %q was accepted", target)
+		}
+		if len(r.Calls()) != 0 {
+			t.Errorf("target %q ran %q", target, r.Calls())
+		}
+	}
+}
+
+func TestCloneOriginShownInList(t *testing.T) {
+	m, _ := newTestModel(t, "healthy")
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	if view := m.View(); !strings.Contains(view, "origin of tank/home-restore") {
+		t.Errorf("origin note missing:\n%s", view)
+	}
+}
+
+func TestDeleteAllSkipsHeldSnapshots(t *testing.T) {
+	m, r := newTestModel(t, "healthy")
+	if got := m.holds["tank/data@zfsguard_2026-02-08"]; len(got) != 1 || got[0] != "keep" {
+		t.Fatalf("holds = %v", m.holds)
+	}
+
+	m, _ = update(t, m, keyPress("D"))
+	if view := m.View(); !strings.Contains(view, "tank/data@zfsguard_2026-02-08 (held by keep)") {
+		t.Errorf("dialog does not list the held snapshot:\n%s", view)
+	}
+
+	r.Reset()
+	m, cmd := update(t, m, keyPress("y"))
+	msg := cmd().(tea.BatchMsg)[0]()
+	if r.Called("zfs destroy tank/data@zfsguard_2026-02-08") {
+		t.Errorf("held snapshot was destroyed: %q", r.Calls())
+	}
+	if !r.Called("zfs destroy tank/data@zfsguard_2026-02-01") {
+		t.Errorf("unheld snapshot was not destroyed: %q", r.Calls())
+	}
+	status := msg.(statusMsg).msg
+	if !strings.Contains(status, "skipped 1: tank/data@zfsguard_2026-02-08 (held by keep)") {
+		t.Errorf("status = %q", status)
+	}
+}
+
+func TestDeleteAllPermissionDeniedListsSkipped(t *testing.T) {
+	m, r := newTestModel(t, "healthy")
+	r.SetRoot(true)
+	r.Set("zfs destroy tank/data@zfsguard_2026-02-01", zfstest.Response{
+		Stderr:   "cannot destroy snapshots: permission denied\n",
+		ExitCode: 1,
+	})
+
+	m, _ = update(t, m, keyPress("D"))
+	_, cmd := update(t, m, keyPress("y"))
+	status := cmd().(tea.BatchMsg)[0]().(statusMsg)
+	if !status.isErr || !strings.Contains(status.msg, "permission denied") {
+		t.Fatalf("status = %+v", status)
+	}
+	if !strings.Contains(status.msg, "skipped 1: tank/data@zfsguard_2026-02-08 (held by keep)") {
+		t.Errorf("status does not list the skipped snapshot: %q", status.msg)
+	}
+}
+
+func TestHoldKey(t *testing.T) {
+	m, r := newTestModel(t, "healthy")
+	r.Reset()
+
+	m, cmd := update(t, m, keyPress("p"))
+	runCmd(cmd)
+	if !r.Called("zfs hold zfsguard tank/data@zfsguard_2026-02-01") {
+		t.Errorf("calls = %q", r.Calls())
+	}
+
+	// The snapshot under the cursor carries no zfsguard hold yet.
+	r.Reset()
+	m, cmd = update(t, m, keyPress("P"))
+	if cmd != nil || !m.statusErr || len(r.Calls()) != 0 {
+		t.Errorf("release without hold ran %q, status %q", r.Calls(), m.statusMsg)
+	}
+}
+
+func TestDeleteDialogShowsReclaimable(t *testing.T) {
+	m, _ := newTestModel(t, "healthy")
+
+	m, cmd := update(t, m, keyPress("d"))
+	if view := m.View(); !strings.Contains(view, "Reclaimable: estimating...") {
+		t.Errorf("dialog does not show the pending estimate:\n%s", view)
+	}
+	m, _ = update(t, m, cmd())
+	if view := m.View(); !strings.Contains(view, "Reclaimable: 1.0M") {
+		t.Errorf("dialog does not show the estimate:\n%s", view)
+	}
+
+	// Delete all includes a snapshot with clones, which zfs refuses.
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	m, cmd = update(t, m, keyPress("D"))
+	m, _ = update(t, m, cmd())
+	if view := m.View(); !strings.Contains(view, "Reclaimable: unknown (failed to estimate") {
+		t.Errorf("dialog does not explain the failed estimate:\n%s", view)
+	}
+}
+
+func TestViewCapacity(t *testing.T) {
+	p := report.PoolReport{Name: "tank", State: "ONLINE", Size: 4 << 40, Free: 3 << 40,
+		Capacity: 31, Fragmentation: 7, DedupRatio: 1, CapacityHealth: "ok"}
+	got := viewCapacity(p)
+	for _, want := range []string{
+		"[" + strings.Repeat("█", 6) + strings.Repeat("░", 14) + "]",
+		"31% of 4.0T (3.0T free), frag 7%, dedup 1.00x",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("viewCapacity = %q, want %q", got, want)
+		}
+	}
+
+	p.Capacity = 120
+	if got := viewCapacity(p); !strings.Contains(got, "["+strings.Repeat("█", 20)+"]") {
+		t.Errorf("viewCapacity over 100%% = %q", got)
+	}
+
+	m := NewModel(config.DefaultConfig(), zfstest.New())
+	m.height = 100
+	m.healthReport = &report.HealthReport{Pools: []report.PoolReport{p}}
+	if view := m.viewHealthReport(); !strings.Contains(view, "120% of 4.0T") {
+		t.Errorf("health view without capacity:\n%s", view)
+	}
+}
+
+func TestViewScan(t *testing.T) {
+	tests := []struct {
+		scan string
+		want string
+	}{
+		{"scrub in progress since Sun Feb  8 00:24:01 2026\n" +
+			"\t1.23T / 4.56T scanned at 512M/s, 1.01T / 4.56T issued at 420M/s\n" +
+			"\t4K repaired, 22.15% done, 02:27:12 to go",
+			"[████░░░░░░░░░░░░░░░░] Scrub 22.15% done, 420M/s, 02:27:12 to go, 4.0K repaired"},
+		{"resilver in progress since Mon Feb  9 10:59:32 2026\n" +
+			"\t640M resilvered, 51.65% done, no estimated completion time",
+			"Resilver 51.65% done, 640M resilvered"},
+		{"scrub paused since Mon Feb  9 08:00:00 2026\n" +
+			"\tscrub started on Sun Feb  8 00:24:01 2026\n\t0B repaired, 22.15% done",
+			"Scrub paused at 22.15% since 2026-02-09 08:00"},
+		{"scrub canceled on Sun Feb  8 00:30:00 2026", "Scrub canceled on 2026-02-08 00:30"},
+		{"scrub repaired 0B in 00:12:31 with 0 errors on Sun Feb  8 00:36:32 2026",
+			"Last scrub 2026-02-08 00:36: repaired 0B, 0 errors"},
+		{"none requested", "Scan: none requested"},
+	}
+	for _, tt := range tests {
+		if got := viewScan(tt.scan); !strings.Contains(got, tt.want) {
+			t.Errorf("viewScan(%q) = %q, want %q", tt.scan, got, tt.want)
+		}
+	}
+}
+
+func TestHealthViewScrubControl(t *testing.T) {
+	m, r := newTestModel(t, "healthy")
+	r.Set("zpool scrub tank", zfstest.Response{})
+	r.Set("zpool scrub -p tank", zfstest.Response{Stderr: "cannot pause scrubbing tank: there is no active scrub\n", ExitCode: 1})
+	m.height = 100
+
+	m, cmd := update(t, m, keyPress("h"))
+	if m.currentView != viewHealth || cmd == nil {
+		t.Fatalf("view = %v", m.currentView)
+	}
+	m, _ = update(t, m, healthLoadedMsg{gen: m.healthGen, report: &report.HealthReport{
+		Pools: []report.PoolReport{{Name: "tank", State: "ONLINE"}}}})
+	m, cmd = update(t, m, scansLoadedMsg{gen: m.scanGen, scans: map[string]string{
+		"tank": "scrub in progress since Sun Feb  8 00:24:01 2026\n\t4K repaired, 22.15% done, 02:27:12 to go"}})
+	if cmd == nil {
+		t.Error("scan refresh not scheduled")
+	}
+	if view := m.View(); !strings.Contains(view, "Scrub 22.15% done") || !strings.Contains(view, "> ") {
+		t.Errorf("health view without progress or selected pool:\n%s", view)
+	}
+
+	r.Reset()
+	m, cmd = update(t, m, keyPress("s"))
+	m, _ = update(t, m, cmd())
+	if !r.Called("zpool scrub tank") || m.statusErr || m.statusMsg != "Started scrub of tank" {
+		t.Errorf("calls = %q, status = %q", r.Calls(), m.statusMsg)
+	}
+	// The scan state is read again right after the scrub started.
+	if !strings.HasPrefix(m.scans["tank"], "scrub repaired 0B") {
+		t.Errorf("scans = %q", m.scans)
+	}
+
+	m, cmd = update(t, m, keyPress("S"))
+	m, _ = update(t, m, cmd())
+	if !m.statusErr || !strings.Contains(m.statusMsg, "Failed to pause scrub of tank") {
+		t.Errorf("status = %q", m.statusMsg)
+	}
+
+	// Leaving the view stops the refresh loop.
+	gen := m.scanGen
+	m, _ = update(t, m, keyPress("h"))
+	if _, cmd = update(t, m, scanTickMsg{gen: gen}); cmd != nil {
+		t.Error("scan refresh continued after leaving the health view")
+	}
+}
+
+// openHealth presses h and applies the results of the loads it starts.
+func openHealth(t *testing.T, m Model) (Model, tea.Cmd) {
+	t.Helper()
+	m, cmd := update(t, m, keyPress("h"))
+	var next tea.Cmd
+	for _, c := range cmd().(tea.BatchMsg) {
+		if msg, ok := c().(healthLoadedMsg); ok {
+			m, next = update(t, m, msg)
+		}
+	}
+	return m, next
+}
+
+func TestHealthViewLiveMode(t *testing.T) {
+	m, r := newTestModel(t, "healthy")
+	r.SetRoot(true)
+	m.height = 100
+
+	// Without a report the view starts live; once the source is picked
+	// with 'l', the missing report is shown instead.
+	m, cmd := openHealth(t, m)
+	if !m.healthLive || cmd == nil {
+		t.Fatalf("live = %v", m.healthLive)
+	}
+	m, _ = update(t, m, cmd())
+	m, cmd = update(t, m, keyPress("l"))
+	m, _ = update(t, m, cmd())
+	if m.healthLive || !strings.Contains(m.View(), "Press 'l' to run the checks live") {
+		t.Fatalf("live = %v, view:\n%s", m.healthLive, m.View())
+	}
+
+	m, cmd = update(t, m, keyPress("l"))
+	if !m.healthLive || !m.healthLoading {
+		t.Fatalf("live = %v, loading = %v", m.healthLive, m.healthLoading)
+	}
+	msg := cmd()
+	// A report file load that finishes after switching is dropped.
+	m, _ = update(t, m, healthLoadedMsg{gen: m.healthGen - 1})
+	m, cmd = update(t, m, msg)
+	if cmd == nil {
+		t.Error("live refresh not scheduled")
+	}
+	view := m.View()
+	for _, want := range []string{"LIVE", "refreshed every 30s", "tank", "/dev/sda"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("live view does not show %q:\n%s", want, view)
+		}
+	}
+
+	if _, cmd = update(t, m, healthTickMsg{gen: m.healthGen}); cmd == nil {
+		t.Error("tick did not re-run the checks")
+	} else if msg, ok := cmd().(healthLoadedMsg); !ok || !msg.live || msg.report == nil {
+		t.Errorf("tick ran %T %+v", msg, msg)
+	}
+
+	m, _ = update(t, m, keyPress("l"))
+	if m.healthLive {
+		t.Error("l did not switch back to the monitor report")
+	}
+	if _, cmd = update(t, m, healthTickMsg{gen: m.healthGen}); cmd != nil {
+		t.Error("live refresh continued after switching back")
+	}
+}
+
+func TestHealthViewGoesLiveWithoutReport(t *testing.T) {
+	m, r := newTestModel(t, "healthy")
+	m.height = 100
+
+	m, cmd := openHealth(t, m)
+	if !m.healthLive || cmd == nil {
+		t.Fatalf("live = %v", m.healthLive)
+	}
+	m, _ = update(t, m, cmd())
+	if m.healthReport == nil || len(m.healthReport.Pools) != 1 {
+		t.Errorf("live report = %+v", m.healthReport)
+	}
+	if view := m.View(); !strings.Contains(view, "SMART data is unavailable without root") {
+		t.Errorf("SMART not marked unavailable:\n%s", view)
+	}
+	if r.Called("smartctl --scan") {
+		t.Error("smartctl ran without root")
+	}
+}
+
+func TestHealthViewStaleReport(t *testing.T) {
+	m := NewModel(config.DefaultConfig(), zfstest.New())
+	m.height = 100
+	m.healthReport = &report.HealthReport{Timestamp: time.Now().Add(-3 * time.Hour)}
+	if view := m.viewHealthReport(); !strings.Contains(view, "STALE") ||
+		!strings.Contains(view, "older than 2h0m0s: zfsguard-monitor may have stopped") {
+		t.Errorf("stale report not highlighted:\n%s", view)
+	}
+
+	m.healthReport.Timestamp = time.Now().Add(-time.Hour)
+	if view := m.viewHealthReport(); strings.Contains(view, "STALE") {
+		t.Errorf("fresh report highlighted as stale:\n%s", view)
+	}
+
+	// Live checks are never stale.
+	m.healthLive = true
+	m.healthReport.Timestamp = time.Now().Add(-3 * time.Hour)
+	if view := m.viewHealthReport(); strings.Contains(view, "STALE") {
+		t.Errorf("live report highlighted as stale:\n%s", view)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/pbek/zfsguard/internal/report"
+	"github.com/pbek/zfsguard/internal/zfs"
 )
 
 // Styles
@@ -87,6 +89,10 @@ var (
 			Bold(true).
 			Foreground(lipgloss.Color("#FF4444"))
 
+	warningStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("#FFAA00"))
+
 	healthLabelStyle = lipgloss.NewStyle().
 				Bold(true).
 				Foreground(lipgloss.Color("#AAAAAA"))
@@ -117,6 +123,12 @@ func (m Model) View() string {
 		b.WriteString(m.viewList())
 		b.WriteString("\n")
 		b.WriteString(m.viewConfirmDeleteAll())
+	case viewClone:
+		b.WriteString(m.viewClone())
+	case viewConfirmRollback:
+		b.WriteString(m.viewList())
+		b.WriteString("\n")
+		b.WriteString(m.viewConfirmRollback())
 	case viewHealth:
 		b.WriteString(m.viewHealthReport())
 	default:
@@ -175,6 +187,18 @@ func (m Model) viewList() string {
 	if selected > 0 {
 		info += fmt.Sprintf(" | %d selected", selected)
 	}
+	// Clone relationship and holds of the snapshot under the cursor
+	if idx := m.currentIndex(); idx >= 0 {
+		if tags := m.holds[m.snapshots[idx].Name]; len(tags) > 0 {
+			info += " | held by " + strings.Join(tags, ", ")
+		}
+		if clones := m.snapshots[idx].Clones; len(clones) > 0 {
+			info += fmt.Sprintf(
+				" | origin of %s (promote or destroy before deleting)",
+				strings.Join(clones, ", "),
+			)
+		}
+	}
 	b.WriteString(countStyle.Render(info))
 	b.WriteString("\n")
 
@@ -182,13 +206,15 @@ func (m Model) viewList() string {
 
 	// Column headers
 	header := fmt.Sprintf(
-		"  %-5s %-*s %-10s %-10s %s",
+		"  %-5s %-*s %-10s %-10s %-16s %-6s %s",
 		"Sel",
 		nameWidth,
 		"Name",
 		"Used",
 		"Refer",
 		"Created",
+		"Clones",
+		"Holds",
 	)
 	b.WriteString(headerStyle.Render(header))
 	b.WriteString("\n")
@@ -269,7 +295,21 @@ func (m Model) viewList() string {
 			padLen = 0
 		}
 		coloredLine += formattedName + strings.Repeat(" ", padLen)
-		coloredLine += fmt.Sprintf(" %-10s %-10s %s", snap.Used, snap.Refer, created)
+		clones, holds := "", ""
+		if len(snap.Clones) > 0 {
+			clones = fmt.Sprintf("%d", len(snap.Clones))
+		}
+		if snap.UserRefs > 0 {
+			holds = fmt.Sprintf("%d", snap.UserRefs)
+		}
+		coloredLine += fmt.Sprintf(
+			" %-10s %-10s %-16s %-6s %s",
+			snap.Used,
+			snap.Refer,
+			created,
+			clones,
+			holds,
+		)
 
 		if vi == m.cursor {
 			b.WriteString(cursorStyle.Render(coloredLine))
@@ -315,14 +355,53 @@ func (m Model) viewCreate() string {
 	return b.String()
 }
 
+func (m Model) viewClone() string {
+	var b strings.Builder
+
+	b.WriteString("\n")
+
+	label := func(focus int, text string) string {
+		if m.cloneFocus == focus {
+			return filterStyle.Render("> " + text)
+		}
+		return "  " + text
+	}
+
+	readonly := uncheckMark + " off"
+	if m.cloneReadonly {
+		readonly = checkMark + " on"
+	}
+
+	content := "Clone Snapshot\n\n"
+	content += "Source snapshot:\n"
+	content += datasetStyle.Render("  "+m.cloneSource) + "\n\n"
+	content += label(0, "Target dataset:") + "\n"
+	content += "  " + m.cloneTarget.View() + "\n"
+	content += label(1, "Mountpoint (empty to inherit):") + "\n"
+	content += "  " + m.cloneMountpoint.View() + "\n"
+	content += label(2, "Read-only (Space to toggle): ") + readonly + "\n\n"
+	content += fmt.Sprintf(
+		"Will create: %s\n(origin %s)\n\n",
+		m.cloneTarget.Value(),
+		m.cloneSource,
+	)
+	content += "Tab/Shift+Tab to switch field | Enter to confirm | Esc to cancel"
+
+	b.WriteString(createDialogStyle.Render(content))
+	return b.String()
+}
+
 func (m Model) nameColumnWidth() int {
 	const (
 		minWidth     = 20
 		usedWidth    = 10
 		referWidth   = 10
 		createdWidth = 16
+		clonesWidth  = 6
+		holdsWidth   = 5
 	)
-	fixed := 2 + 5 + 1 + 1 + usedWidth + 1 + referWidth + 1 + createdWidth
+	fixed := 2 + 5 + 1 + 1 + usedWidth + 1 + referWidth + 1 + createdWidth + 1 + clonesWidth +
+		1 + holdsWidth
 	width := m.width - fixed
 	if width < minWidth {
 		return minWidth
@@ -337,25 +416,107 @@ func min(a, b int) int {
 	return b
 }
 
+// viewSkipped lists snapshots a delete will leave alone.
+func viewSkipped(skipped []skippedSnapshot) string {
+	if len(skipped) == 0 {
+		return ""
+	}
+	content := fmt.Sprintf("\n%d held snapshot(s) will be skipped:\n", len(skipped))
+	for i, s := range skipped {
+		if i >= 5 {
+			content += fmt.Sprintf("  ... and %d more\n", len(skipped)-5)
+			break
+		}
+		content += fmt.Sprintf("  - %s (%s)\n", s.name, s.reason)
+	}
+	return content
+}
+
+// viewReclaimable shows the space the pending delete is expected to free.
+func (m Model) viewReclaimable() string {
+	switch {
+	case m.estimateFor == "":
+		return ""
+	case m.estimateLoading:
+		return "\nReclaimable: estimating...\n"
+	case m.estimateErr != nil:
+		// zfs explains refusals over several lines; the first one is enough.
+		reason, _, _ := strings.Cut(m.estimateErr.Error(), "\n")
+		return fmt.Sprintf("\nReclaimable: unknown (%s)\n", reason)
+	}
+	return fmt.Sprintf("\nReclaimable: %s\n", zfs.FormatBytes(m.estimateBytes))
+}
+
 func (m Model) viewConfirmDelete() string {
-	selected := m.selectedSnapshots()
-	content := fmt.Sprintf("Delete %d selected snapshot(s)?\n\n", len(selected))
-	for i, s := range selected {
+	toDelete, skipped := m.deleteTargets()
+	content := fmt.Sprintf("Delete %d selected snapshot(s)?\n\n", len(toDelete))
+	for i, name := range toDelete {
 		if i >= 10 {
-			content += fmt.Sprintf("  ... and %d more\n", len(selected)-10)
+			content += fmt.Sprintf("  ... and %d more\n", len(toDelete)-10)
 			break
 		}
-		content += fmt.Sprintf("  - %s\n", s.Name)
+		content += fmt.Sprintf("  - %s\n", name)
 	}
+	content += m.viewReclaimable()
+	content += viewSkipped(skipped)
 	content += "\nThis action requires elevated privileges.\n"
 	content += "Press 'y' to confirm, 'n'/Esc to cancel"
 	return dialogStyle.Render(content)
 }
 
 func (m Model) viewConfirmDeleteAll() string {
-	content := fmt.Sprintf("DELETE ALL %d SNAPSHOTS?\n\n", len(m.snapshots))
+	toDelete, skipped := m.deleteTargets()
+	content := fmt.Sprintf("DELETE ALL %d SNAPSHOTS?\n\n", len(toDelete))
 	content += "This will destroy every snapshot on the system.\n"
-	content += "This action is IRREVERSIBLE and requires elevated privileges.\n\n"
+	content += "This action is IRREVERSIBLE and requires elevated privileges.\n"
+	content += m.viewReclaimable()
+	content += viewSkipped(skipped)
+	content += "\nPress 'y' to confirm, 'n'/Esc to cancel"
+	return dialogStyle.Render(content)
+}
+
+func (m Model) viewConfirmRollback() string {
+	content := fmt.Sprintf("Roll back to %s?\n\n", m.rollbackTarget)
+
+	switch {
+	case m.rollbackLoading:
+		content += "Checking for newer snapshots and bookmarks...\n\n"
+		content += "Press 'n'/Esc to cancel"
+		return dialogStyle.Render(content)
+	case m.rollbackErr != nil:
+		content += fmt.Sprintf("Cannot preview rollback: %v\n\n", m.rollbackErr)
+		content += "Press 'n'/Esc to cancel"
+		return dialogStyle.Render(content)
+	}
+
+	if blockers := m.rollbackBlockers(); len(blockers) > 0 {
+		content += "Cannot roll back: these newer snapshots have clones and\n"
+		content += "would have to be destroyed together with them:\n\n"
+		for _, item := range blockers {
+			content += fmt.Sprintf("  - %s\n    clones: %s\n", item.Name, strings.Join(item.Clones, ", "))
+		}
+		content += "\nPromote or destroy the clones first.\n\n"
+		content += "Press 'n'/Esc to cancel"
+		return dialogStyle.Render(content)
+	}
+
+	content += "All changes made to the dataset since this snapshot will be lost.\n"
+	if len(m.rollbackNewer) == 0 {
+		content += "No newer snapshots or bookmarks exist.\n"
+	} else {
+		content += fmt.Sprintf(
+			"The following %d newer snapshot(s)/bookmark(s) will be DESTROYED:\n\n",
+			len(m.rollbackNewer),
+		)
+		for i, item := range m.rollbackNewer {
+			if i >= 10 {
+				content += fmt.Sprintf("  ... and %d more\n", len(m.rollbackNewer)-10)
+				break
+			}
+			content += fmt.Sprintf("  - %s\n", item.Name)
+		}
+	}
+	content += "\nThis action is IRREVERSIBLE and requires elevated privileges.\n"
 	content += "Press 'y' to confirm, 'n'/Esc to cancel"
 	return dialogStyle.Render(content)
 }
@@ -364,8 +525,12 @@ func (m Model) viewHealthReport() string {
 	var lines []string
 
 	if m.healthLoading {
+		text := "  Loading health report..."
+		if m.healthLive {
+			text = "  Running health checks..."
+		}
 		lines = append(lines, "")
-		lines = append(lines, healthLabelStyle.Render("  Loading health report..."))
+		lines = append(lines, healthLabelStyle.Render(text))
 		lines = append(lines, "")
 		return strings.Join(lines, "\n")
 	}
@@ -383,6 +548,7 @@ func (m Model) viewHealthReport() string {
 			healthDimStyle.Render("  and has completed at least one health check cycle."),
 		)
 		lines = append(lines, "")
+		lines = append(lines, healthDimStyle.Render("  Press 'l' to run the checks live (SMART needs root)"))
 		lines = append(lines, healthDimStyle.Render("  Press 'r' to retry | 'h'/Esc to go back"))
 		return strings.Join(lines, "\n") + "\n"
 	}
@@ -392,9 +558,18 @@ func (m Model) viewHealthReport() string {
 	// Report timestamp
 	lines = append(lines, "")
 	age := time.Since(r.Timestamp).Truncate(time.Second)
-	lines = append(lines,
-		healthDimStyle.Render(fmt.Sprintf("  Last check: %s (%s ago)",
-			r.Timestamp.Format("2006-01-02 15:04:05"), age)))
+	lastCheck := fmt.Sprintf("  Last check: %s (%s ago)", r.Timestamp.Format("2006-01-02 15:04:05"), age)
+	if !m.healthLive && r.Stale(time.Now(), m.staleAfter) {
+		// A monitor that stopped leaves its last report behind; don't let
+		// an old "all healthy" look current.
+		lines = append(lines, unhealthyStyle.Render(lastCheck+"  STALE"))
+		lines = append(lines, unhealthyStyle.Render(fmt.Sprintf(
+			"  ! The report is older than %s: zfsguard-monitor may have stopped.", m.staleAfter)))
+		lines = append(lines, warningStyle.Render("  ! What follows may be outdated; press 'l' for live checks."))
+	} else {
+		lines = append(lines, healthDimStyle.Render(lastCheck))
+	}
+	lines = append(lines, m.viewHealthSource())
 	lines = append(lines, "")
 
 	// ZFS Pool Health section
@@ -410,19 +585,30 @@ func (m Model) viewHealthReport() string {
 		lines = append(lines, healthDimStyle.Render("  No pools found."))
 		lines = append(lines, "")
 	} else {
+		selected := m.selectedHealthPool()
 		for _, pool := range r.Pools {
 			stateLabel := healthyStyle.Render(pool.State)
 			if pool.State != "ONLINE" {
 				stateLabel = unhealthyStyle.Render(pool.State)
 			}
 
+			marker := "  "
+			if pool.Name == selected {
+				marker = selectedStyle.Render("> ")
+			}
 			lines = append(lines,
-				fmt.Sprintf("  %s  %s",
+				fmt.Sprintf("%s%s  %s",
+					marker,
 					healthLabelStyle.Render(fmt.Sprintf("%-20s", pool.Name)),
 					stateLabel,
 				),
 			)
 
+			if pool.Size > 0 {
+				lines = append(lines, fmt.Sprintf("  %s  %s",
+					healthDimStyle.Render(fmt.Sprintf("%-20s", "")), viewCapacity(pool)))
+			}
+
 			errText := pool.Errors
 			if errText == "" {
 				errText = "No known data errors"
@@ -443,6 +629,27 @@ func (m Model) viewHealthReport() string {
 				)
 			}
 
+			indent := healthDimStyle.Render(fmt.Sprintf("%-20s", ""))
+			scan, live := m.scans[pool.Name]
+			if !live {
+				scan = pool.Scan
+			}
+			if scan != "" {
+				lines = append(lines, fmt.Sprintf("  %s  %s", indent, viewScan(scan)))
+			}
+			for _, d := range pool.FaultedDevices() {
+				text := fmt.Sprintf("Device %s %s (read %d, write %d, cksum %d)",
+					d.Name, d.State, d.Read, d.Write, d.Checksum)
+				if d.Message != "" {
+					text += " " + d.Message
+				}
+				lines = append(lines, fmt.Sprintf("  %s  %s", indent, unhealthyStyle.Render(text)))
+			}
+			if pool.Action != "" {
+				lines = append(lines,
+					fmt.Sprintf("  %s  %s", indent, healthValueStyle.Render("Action: "+pool.Action)))
+			}
+
 			// Render raw zpool status output (indented and dimmed)
 			if pool.Raw != "" {
 				lines = append(lines, "")
@@ -473,8 +680,13 @@ func (m Model) viewHealthReport() string {
 	} else {
 		for _, disk := range r.Disks {
 			statusLabel := healthyStyle.Render("HEALTHY")
-			if !disk.Healthy {
-				statusLabel = unhealthyStyle.Render("UNHEALTHY")
+			issueStyle := warningStyle
+			switch disk.Level() {
+			case report.LevelWarning:
+				statusLabel = warningStyle.Render("WARNING")
+			case report.LevelCritical:
+				statusLabel = unhealthyStyle.Render("CRITICAL")
+				issueStyle = unhealthyStyle
 			}
 
 			lines = append(lines,
@@ -484,11 +696,16 @@ func (m Model) viewHealthReport() string {
 					healthValueStyle.Render(disk.Summary),
 				),
 			)
+			for _, issue := range disk.Issues {
+				lines = append(lines, issueStyle.Render("  ! "+issue))
+			}
 
-			// Render raw smartctl output (indented and dimmed)
-			if disk.Raw != "" {
+			if disk.Attributes != nil {
+				lines = append(lines, viewSMARTAttributes(disk.Attributes)...)
+			} else if disk.Raw != "" {
+				// Render raw smartctl output (indented and dimmed)
 				lines = append(lines, "")
-				lines = append(lines, healthLabelStyle.Render("  Detailed status (smartctl -H "+disk.Device+"):"))
+				lines = append(lines, healthLabelStyle.Render("  Detailed status (smartctl "+disk.Device+"):"))
 				lines = append(lines, headerStyle.Render("  "+strings.Repeat("─", min(m.width-4, 80))))
 				for _, rawLine := range strings.Split(strings.TrimRight(disk.Raw, "\n"), "\n") {
 					lines = append(lines, healthDimStyle.Render("  "+rawLine))
@@ -500,8 +717,38 @@ func (m Model) viewHealthReport() string {
 		}
 	}
 
+	// Snapshot pruning section
+	if p := r.Pruning; p != nil {
+		title := "  Snapshot Pruning"
+		if p.DryRun {
+			title += " (dry run)"
+		}
+		lines = append(lines, healthTitleStyle.Render(title))
+		lines = append(lines, "")
+		if p.Error != "" {
+			lines = append(lines, unhealthyStyle.Render("  Error: "+p.Error))
+		}
+		lines = append(lines, healthValueStyle.Render(fmt.Sprintf(
+			"  Pruned %d, kept %d, failed %d", len(p.Destroyed), p.Kept, len(p.Failed))))
+		for _, name := range p.Destroyed {
+			lines = append(lines, healthDimStyle.Render("    - "+name))
+		}
+		for _, f := range p.Failed {
+			lines = append(lines, unhealthyStyle.Render("    ! "+f.Error))
+		}
+		lines = append(lines, "")
+	}
+
 	// Footer hint
-	lines = append(lines, healthDimStyle.Render("  Press 'r' to refresh | 'h'/Esc to go back"))
+	if len(r.Pools) > 0 {
+		lines = append(lines, healthDimStyle.Render(
+			"  Tab: select pool | 's' scrub/resume | 'S' pause | 'X' cancel scrub"))
+	}
+	source := "'l' live checks"
+	if m.healthLive {
+		source = "'l' monitor report"
+	}
+	lines = append(lines, healthDimStyle.Render("  Press 'r' to refresh | "+source+" | 'h'/Esc to go back"))
 
 	// Apply scrolling
 	vpHeight := m.viewportHeight()
@@ -536,3 +783,134 @@ func (m Model) viewHealthReport() string {
 
 	return b.String()
 }
+
+// viewHealthSource tells where the displayed health report comes from.
+func (m Model) viewHealthSource() string {
+	if m.healthLive {
+		return "  " + warningStyle.Render("LIVE") + healthDimStyle.Render(fmt.Sprintf(
+			" checks run by zfsguard, refreshed every %s", liveHealthInterval))
+	}
+	return healthDimStyle.Render("  Source: monitor report " + m.reportPath)
+}
+
+// capacityBarWidth is the width of the pool capacity bar in cells.
+const capacityBarWidth = 20
+
+// viewCapacity renders the pool capacity as a bar colored by the capacity
+// thresholds, followed by the sizes, fragmentation and dedup ratio, e.g.
+// "[██████░░░░░░░░░░░░░░] 31% of 3.6T (2.5T free), frag 7%, dedup 1.00x".
+func viewCapacity(p report.PoolReport) string {
+	bar := progressBar(float64(p.Capacity))
+
+	style := healthyStyle
+	switch p.CapacityHealth {
+	case report.LevelWarning:
+		style = warningStyle
+	case report.LevelCritical:
+		style = unhealthyStyle
+	}
+	text := fmt.Sprintf(" %d%% of %s (%s free), frag %d%%, dedup %.2fx",
+		p.Capacity, zfs.FormatBytes(p.Size), zfs.FormatBytes(p.Free), p.Fragmentation, p.DedupRatio)
+	return style.Render(bar) + healthDimStyle.Render(text)
+}
+
+// progressBar renders percent as a bar of capacityBarWidth cells, e.g.
+// "[██████░░░░░░░░░░░░░░]".
+func progressBar(percent float64) string {
+	filled := min(max(int(percent)*capacityBarWidth/100, 0), capacityBarWidth)
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", capacityBarWidth-filled) + "]"
+}
+
+// viewScan renders the scan text of a pool: the progress of a running
+// scrub or resilver with a bar, a paused or canceled scrub, or the result
+// of the last one. Text that cannot be parsed is shown as is.
+func viewScan(scan string) string {
+	info := zfs.ParseScan(scan)
+	if info.State == "" {
+		first, _, _ := strings.Cut(strings.TrimSpace(scan), "\n")
+		return healthDimStyle.Render("Scan: " + first)
+	}
+	function := strings.ToUpper(info.Function[:1]) + info.Function[1:]
+	verb := "repaired"
+	if info.Function == "resilver" {
+		verb = "resilvered"
+	}
+	switch info.State {
+	case zfs.ScanInProgress:
+		text := fmt.Sprintf(" %s %.2f%% done", function, info.Percent)
+		if info.Rate != "" {
+			text += ", " + info.Rate
+		}
+		if info.ToGo != "" {
+			text += ", " + info.ToGo + " to go"
+		}
+		text += fmt.Sprintf(", %s %s", zfs.FormatBytes(info.Repaired), verb)
+		return healthyStyle.Render(progressBar(info.Percent)) + healthValueStyle.Render(text)
+	case zfs.ScanPaused:
+		return warningStyle.Render(fmt.Sprintf("%s paused at %.2f%% since %s",
+			function, info.Percent, info.End.Format("2006-01-02 15:04")))
+	case zfs.ScanFinished:
+		text := fmt.Sprintf("Last %s %s: %s %s, %d errors",
+			info.Function, info.End.Format("2006-01-02 15:04"), verb, zfs.FormatBytes(info.Repaired), info.Errors)
+		switch {
+		case info.Errors > 0:
+			return unhealthyStyle.Render(text)
+		case info.Repaired > 0 && info.Function == "scrub":
+			return warningStyle.Render(text)
+		}
+		return healthDimStyle.Render(text)
+	}
+	return healthDimStyle.Render(fmt.Sprintf("%s canceled on %s",
+		function, info.End.Format("2006-01-02 15:04")))
+}
+
+// viewSMARTAttributes renders the SMART attributes of a disk: identity and
+// temperature, then the ATA attribute table, NVMe health log or SCSI error
+// counters.
+func viewSMARTAttributes(a *report.SMARTAttributes) []string {
+	var lines []string
+	var info []string
+	if a.Model != "" {
+		info = append(info, a.Model)
+	}
+	if a.Serial != "" {
+		info = append(info, "S/N "+a.Serial)
+	}
+	if a.Temperature != 0 {
+		info = append(info, fmt.Sprintf("%d°C", a.Temperature))
+	}
+	if a.PowerOnHours != 0 {
+		info = append(info, fmt.Sprintf("%dh powered on", a.PowerOnHours))
+	}
+	if len(info) > 0 {
+		lines = append(lines, healthDimStyle.Render("  "+a.Protocol+": "+strings.Join(info, ", ")))
+	}
+
+	if len(a.ATA) > 0 {
+		lines = append(lines, healthLabelStyle.Render(fmt.Sprintf("  %3s  %-24s %5s %5s %6s  %s",
+			"ID", "ATTRIBUTE", "VALUE", "WORST", "THRESH", "RAW")))
+		for _, attr := range a.ATA {
+			line := fmt.Sprintf("  %3d  %-24s %5d %5d %6d  %s",
+				attr.ID, attr.Name, attr.Value, attr.Worst, attr.Threshold, attr.RawString)
+			if attr.WhenFailed != "" {
+				lines = append(lines, unhealthyStyle.Render(line+"  "+attr.WhenFailed))
+				continue
+			}
+			lines = append(lines, healthDimStyle.Render(line))
+		}
+	}
+	if n := a.NVMe; n != nil {
+		lines = append(lines, healthDimStyle.Render(fmt.Sprintf(
+			"  Critical warning 0x%02x, spare %d%% (threshold %d%%), used %d%%",
+			n.CriticalWarning, n.AvailableSpare, n.AvailableSpareThreshold, n.PercentageUsed)))
+		lines = append(lines, healthDimStyle.Render(fmt.Sprintf(
+			"  Media errors %d, error log entries %d, unsafe shutdowns %d",
+			n.MediaErrors, n.ErrorLogEntries, n.UnsafeShutdowns)))
+	}
+	if c := a.SCSI; c != nil {
+		lines = append(lines, healthDimStyle.Render(fmt.Sprintf(
+			"  Uncorrected errors: read %d, write %d, verify %d; grown defects %d",
+			c.ReadUncorrected, c.WriteUncorrected, c.VerifyUncorrected, c.GrownDefects)))
+	}
+	return lines
+}
@@ -0,0 +1,8 @@
+package zfs
+
+import "time"
+
+// SetNow replaces the clock of the client for tests.
+func (c *Client) SetNow(now func() time.Time) {
+	c.now = now
+}
@@ -0,0 +1,77 @@
+package zfs
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Hold is a user hold on a snapshot. A held snapshot cannot be destroyed
+// until every hold on it has been released.
+type Hold struct {
+	Snapshot string
+	Tag      string
+	Created  time.Time
+}
+
+// Holds returns the user holds on the given snapshots.
+func (c *Client) Holds(snapshots ...string) ([]Hold, error) {
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+	args := append([]string{"holds", "-H", "-p"}, snapshots...)
+	out, err := c.runner.Output("zfs", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list holds: %w", err)
+	}
+	return parseHolds(string(out)), nil
+}
+
+// Hold places a user hold with the given tag on each snapshot.
+func (c *Client) Hold(tag string, snapshots ...string) error {
+	args := append([]string{"hold", tag}, snapshots...)
+	return c.runPrivileged(fmt.Sprintf("hold %s with tag %q", joinNames(snapshots), tag), args...)
+}
+
+// Release removes the user hold with the given tag from each snapshot.
+func (c *Client) Release(tag string, snapshots ...string) error {
+	args := append([]string{"release", tag}, snapshots...)
+	return c.runPrivileged(
+		fmt.Sprintf("release hold %q on %s", tag, joinNames(snapshots)),
+		args...,
+	)
+}
+
+func parseHolds(output string) []Hold {
+	var holds []Hold
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		// Format: name\ttag\ttimestamp
+		parts := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		h := Hold{
+			Snapshot: strings.TrimSpace(parts[0]),
+			Tag:      strings.TrimSpace(parts[1]),
+		}
+		if len(parts) == 3 {
+			ts := strings.TrimSpace(parts[2])
+			if parsed, ok := parseInt64(ts); ok {
+				h.Created = time.Unix(parsed, 0)
+			} else {
+				h.Created, _ = parseZFSTime(ts)
+			}
+		}
+		holds = append(holds, h)
+	}
+	return holds
+}
+
+func joinNames(names []string) string {
+	if len(names) == 1 {
+		return fmt.Sprintf("%q", names[0])
+	}
+	return fmt.Sprintf("%d snapshots", len(names))
+}
@@ -0,0 +1,411 @@
+package zfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenZFS 2.3 and later can print JSON (-j) from zfs list, zpool status and
+// friends. It is used when available because it does not depend on column
+// layout; older releases fall back to the text parsers.
+
+// jsonSupported reports whether the installed OpenZFS understands -j. It is
+// detected once per Client with "zfs version -j", which older releases
+// reject as an invalid option.
+func (c *Client) jsonSupported() bool {
+	c.jsonOnce.Do(func() {
+		out, err := c.runner.Output("zfs", "version", "-j")
+		c.jsonOK = err == nil && json.Valid(out)
+	})
+	return c.jsonOK
+}
+
+// jsonString is a JSON value that zfs prints either as a string or, with
+// --json-int, as a number.
+type jsonString string
+
+func (s *jsonString) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*s = jsonString(str)
+		return nil
+	}
+	var num json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return err
+	}
+	*s = jsonString(num.String())
+	return nil
+}
+
+// zfsListJSON is the output of "zfs list -j".
+type zfsListJSON struct {
+	Datasets map[string]struct {
+		Properties map[string]struct {
+			Value jsonString `json:"value"`
+		} `json:"properties"`
+	} `json:"datasets"`
+}
+
+func (c *Client) listSnapshotsJSON() ([]Snapshot, error) {
+	out, err := c.runner.Output("zfs", "list", "-j", "-p", "-t", "snapshot",
+		"-o", "name,used,refer,creation,clones,userrefs", "-s", "creation")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	return parseSnapshotsJSON(out)
+}
+
+func parseSnapshotsJSON(data []byte) ([]Snapshot, error) {
+	var list zfsListJSON
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot list: %w", err)
+	}
+
+	snapshots := make([]Snapshot, 0, len(list.Datasets))
+	for name, d := range list.Datasets {
+		// Properties are keyed by their full name ("referenced" for refer).
+		prop := func(names ...string) string {
+			for _, n := range names {
+				if p, ok := d.Properties[n]; ok {
+					return string(p.Value)
+				}
+			}
+			return ""
+		}
+		snapshots = append(snapshots, snapshotFromFields([]string{
+			name,
+			prop("used"),
+			prop("referenced", "refer"),
+			prop("creation"),
+			prop("clones"),
+			prop("userrefs"),
+		}))
+	}
+	// JSON objects are unordered; restore the "-s creation" order.
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if !snapshots[i].Creation.Equal(snapshots[j].Creation) {
+			return snapshots[i].Creation.Before(snapshots[j].Creation)
+		}
+		return snapshots[i].Name < snapshots[j].Name
+	})
+	return snapshots, nil
+}
+
+func (c *Client) listDatasetsJSON() ([]string, error) {
+	out, err := c.runner.Output("zfs", "list", "-j", "-o", "name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list datasets: %w", err)
+	}
+	var list zfsListJSON
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse dataset list: %w", err)
+	}
+	datasets := make([]string, 0, len(list.Datasets))
+	for name := range list.Datasets {
+		datasets = append(datasets, name)
+	}
+	sort.Strings(datasets)
+	return datasets, nil
+}
+
+// vdevJSON is a vdev in "zpool status -j" output.
+type vdevJSON struct {
+	State          string              `json:"state"`
+	ReadErrors     jsonString          `json:"read_errors"`
+	WriteErrors    jsonString          `json:"write_errors"`
+	ChecksumErrors jsonString          `json:"checksum_errors"`
+	Was            string              `json:"was"`
+	Vdevs          map[string]vdevJSON `json:"vdevs"`
+}
+
+// scanJSON holds the scan_stats of a pool in "zpool status -j" output.
+type scanJSON struct {
+	Function  string     `json:"function"`
+	State     string     `json:"state"`
+	StartTime jsonString `json:"start_time"`
+	EndTime   jsonString `json:"end_time"`
+	ToExamine jsonString `json:"to_examine"`
+	Examined  jsonString `json:"examined"`
+	Skipped   jsonString `json:"skipped"`
+	Issued    jsonString `json:"issued"`
+	Processed jsonString `json:"processed"`
+	Errors    jsonString `json:"errors"`
+	// The current pass, which restarts when a paused scrub is resumed.
+	PassStart    jsonString `json:"pass_start"`
+	PassExamined jsonString `json:"pass_exam"`
+	PassIssued   jsonString `json:"issued_bytes_per_scan"`
+	// ScrubPause is when a paused scrub was paused, "-" when it runs.
+	ScrubPause  jsonString `json:"scrub_pause"`
+	SpentPaused jsonString `json:"scrub_spent_paused"`
+}
+
+// zpoolStatusJSON is the output of "zpool status -j".
+type zpoolStatusJSON struct {
+	Pools map[string]struct {
+		State      string              `json:"state"`
+		Status     string              `json:"status"`
+		Action     string              `json:"action"`
+		ErrorCount jsonString          `json:"error_count"`
+		ScanStats  *scanJSON           `json:"scan_stats"`
+		Vdevs      map[string]vdevJSON `json:"vdevs"`
+		Logs       map[string]vdevJSON `json:"logs"`
+		Special    map[string]vdevJSON `json:"special"`
+		Dedup      map[string]vdevJSON `json:"dedup"`
+		L2Cache    map[string]vdevJSON `json:"l2cache"`
+		Spares     map[string]vdevJSON `json:"spares"`
+	} `json:"pools"`
+}
+
+func (c *Client) poolStatusesJSON() ([]PoolStatus, error) {
+	out, err := c.runner.Output("zpool", "status", "-j", "-p")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool status: %w", err)
+	}
+	statuses, err := parsePoolStatusesJSON(out, c.now())
+	if err != nil {
+		return nil, err
+	}
+	// The text output is still shown to users as the detailed status.
+	for i := range statuses {
+		if raw, err := c.runner.Output("zpool", "status", statuses[i].Name); err == nil {
+			statuses[i].Raw = string(raw)
+		}
+	}
+	return statuses, nil
+}
+
+func parsePoolStatusesJSON(data []byte, now time.Time) ([]PoolStatus, error) {
+	var st zpoolStatusJSON
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("failed to parse pool status: %w", err)
+	}
+
+	statuses := make([]PoolStatus, 0, len(st.Pools))
+	for name, p := range st.Pools {
+		status := PoolStatus{
+			Name:   name,
+			State:  p.State,
+			Status: p.Status,
+			Action: p.Action,
+			Errors: "No known data errors",
+		}
+		if n, _ := strconv.ParseUint(string(p.ErrorCount), 10, 64); n > 0 {
+			status.Errors = fmt.Sprintf("%d data errors, use '-v' for a list", n)
+		}
+		status.Scan = p.ScanStats.summary(now)
+
+		status.Config = vdevNodes(p.Vdevs, 0)
+		sections := []struct {
+			name  string
+			vdevs map[string]vdevJSON
+		}{
+			{"logs", p.Logs},
+			{"special", p.Special},
+			{"dedup", p.Dedup},
+			{"cache", p.L2Cache},
+			{"spares", p.Spares},
+		}
+		for _, sec := range sections {
+			if len(sec.vdevs) > 0 {
+				status.Config = append(status.Config, VdevNode{
+					Name:     sec.name,
+					Type:     sec.name,
+					Children: vdevNodes(sec.vdevs, 1),
+				})
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// vdevNodes converts a JSON vdev map, sorted by name, into VdevNodes.
+func vdevNodes(vdevs map[string]vdevJSON, depth int) []VdevNode {
+	if len(vdevs) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(vdevs))
+	for name := range vdevs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	nodes := make([]VdevNode, 0, len(names))
+	for _, name := range names {
+		v := vdevs[name]
+		read, _ := parseErrorCount(string(v.ReadErrors))
+		write, _ := parseErrorCount(string(v.WriteErrors))
+		cksum, _ := parseErrorCount(string(v.ChecksumErrors))
+		node := VdevNode{
+			Name:     name,
+			Type:     vdevType(name, depth),
+			State:    v.State,
+			Read:     read,
+			Write:    write,
+			Checksum: cksum,
+			Children: vdevNodes(v.Vdevs, depth+1),
+		}
+		if v.Was != "" {
+			node.Message = "was " + v.Was
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// summary renders scan stats like the "scan:" text of zpool status, so
+// ParseScan understands both. A pool that was never scanned has no or "NONE"
+// stats, which the text output shows as "none requested". now is used for
+// the rate and remaining time of a running scan.
+func (s *scanJSON) summary(now time.Time) string {
+	if s == nil || s.State == "" || s.State == "NONE" {
+		return "none requested"
+	}
+	function := strings.ToLower(s.Function)
+	processed := string(s.Processed)
+	if n, err := strconv.ParseUint(processed, 10, 64); err == nil {
+		processed = FormatBytes(n)
+	}
+	errors := string(s.Errors)
+	if errors == "" {
+		errors = "0"
+	}
+	start, end := scanTimeJSON(s.StartTime), scanTimeJSON(s.EndTime)
+
+	switch s.State {
+	case "SCANNING":
+		return s.progress(function, start, now)
+	case "FINISHED":
+		if function == "resilver" {
+			return fmt.Sprintf("resilvered %s with %s errors on %s", processed, errors, end)
+		}
+		return fmt.Sprintf("%s repaired %s with %s errors on %s", function, processed, errors, end)
+	case "CANCELED":
+		return fmt.Sprintf("%s canceled on %s", function, end)
+	}
+	return ""
+}
+
+// progress renders a running or paused scan, e.g.
+//
+//	scrub in progress since Sun Feb  8 00:24:01 2026
+//	1.2G / 4.3G scanned, 1.0G / 4.3G issued at 420M/s
+//	0B repaired, 22.15% done, 00:08:12 to go
+//
+// or, for a paused scrub, "scrub paused since <time>" followed by "scrub
+// started on <time>" and the same lines without rate and remaining time.
+func (s *scanJSON) progress(function, start string, now time.Time) string {
+	paused := scanTimeJSON(s.ScrubPause)
+	isPaused := paused != "" && paused != "-"
+
+	var lines []string
+	if isPaused {
+		lines = append(lines,
+			fmt.Sprintf("%s paused since %s", function, paused),
+			fmt.Sprintf("%s started on %s", function, start))
+	} else {
+		lines = append(lines, fmt.Sprintf("%s in progress since %s", function, start))
+	}
+
+	toExamine, totalOK := parseUintJSON(s.ToExamine)
+	examined, examinedOK := parseUintJSON(s.Examined)
+	issued, issuedOK := parseUintJSON(s.Issued)
+	skipped, _ := parseUintJSON(s.Skipped)
+
+	// The issue rate of the current pass drives the remaining time, as in
+	// zpool status.
+	var rate float64
+	if !isPaused {
+		passIssued, ok := parseUintJSON(s.PassIssued)
+		if !ok {
+			passIssued, ok = parseUintJSON(s.PassExamined)
+		}
+		spent, _ := parseUintJSON(s.SpentPaused)
+		passStart := parseTimeJSON(s.PassStart)
+		if ok && !passStart.IsZero() {
+			elapsed := now.Sub(passStart).Seconds() - float64(spent)
+			rate = float64(passIssued) / max(elapsed, 1)
+		}
+	}
+
+	if totalOK && examinedOK && issuedOK {
+		line := fmt.Sprintf("%s / %s scanned, %s / %s issued",
+			FormatBytes(examined), FormatBytes(toExamine), FormatBytes(issued), FormatBytes(toExamine))
+		if rate > 0 {
+			line += fmt.Sprintf(" at %s/s", FormatBytes(uint64(rate)))
+		}
+		lines = append(lines, line)
+	}
+
+	// Like zpool status, progress counts issued data when it is reported.
+	var done []string
+	if n, ok := parseUintJSON(s.Processed); ok {
+		verb := "repaired"
+		if function == "resilver" {
+			verb = "resilvered"
+		}
+		done = append(done, fmt.Sprintf("%s %s", FormatBytes(n), verb))
+	}
+	if issuedOK && totalOK && toExamine > skipped {
+		total := toExamine - skipped
+		done = append(done, fmt.Sprintf("%.2f%% done", float64(issued)/float64(total)*100))
+		if rate > 0 && total > issued {
+			done = append(done, formatDHMS(uint64(float64(total-issued)/rate))+" to go")
+		}
+	} else if examinedOK && totalOK && toExamine > 0 {
+		done = append(done, fmt.Sprintf("%.2f%% done", float64(examined)/float64(toExamine)*100))
+	}
+	if len(done) > 0 {
+		lines = append(lines, strings.Join(done, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseUintJSON parses a byte count or counter of zpool status -j -p.
+func parseUintJSON(v jsonString) (uint64, bool) {
+	n, err := strconv.ParseUint(string(v), 10, 64)
+	return n, err == nil
+}
+
+// formatDHMS formats seconds like zpool status does for the remaining time
+// of a scan, e.g. "02:27:12" or "1 days 02:27:12".
+func formatDHMS(secs uint64) string {
+	days, secs := secs/86400, secs%86400
+	hms := fmt.Sprintf("%02d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	if days > 0 {
+		return fmt.Sprintf("%d days %s", days, hms)
+	}
+	return hms
+}
+
+// scanTimeJSON renders a scan time like the text output does. With -p, zpool
+// status -j may print times as seconds since the epoch instead of text.
+func scanTimeJSON(v jsonString) string {
+	str := string(v)
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return str
+	}
+	if n <= 0 {
+		return "-"
+	}
+	return time.Unix(n, 0).In(time.Local).Format("Mon Jan _2 15:04:05 2006")
+}
+
+// parseTimeJSON parses a scan time of zpool status -j, either seconds since
+// the epoch or text. It is zero for "-" or when not understood.
+func parseTimeJSON(v jsonString) time.Time {
+	if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+		if n <= 0 {
+			return time.Time{}
+		}
+		return time.Unix(n, 0)
+	}
+	return parseScanTime(string(v))
+}
@@ -0,0 +1,219 @@
+package zfs_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/zfs"
+	"github.com/pbek/zfsguard/internal/zfs/zfstest"
+)
+
+// The healthy and healthy-json scenarios describe the same system; both
+// parsing paths must agree on everything but the rendering of the scan line.
+func TestJSONMatchesText(t *testing.T) {
+	text, _ := scenario(t, "healthy")
+	js, r := scenario(t, "healthy-json")
+
+	textSnaps, err := text.ListSnapshots()
+	if err != nil {
+		t.Fatalf("text ListSnapshots: %v", err)
+	}
+	jsonSnaps, err := js.ListSnapshots()
+	if err != nil {
+		t.Fatalf("JSON ListSnapshots: %v", err)
+	}
+	if !reflect.DeepEqual(textSnaps, jsonSnaps) {
+		t.Errorf("snapshots differ:\ntext %+v\njson %+v", textSnaps, jsonSnaps)
+	}
+
+	textDatasets, _ := text.ListDatasets()
+	jsonDatasets, err := js.ListDatasets()
+	if err != nil || !reflect.DeepEqual(textDatasets, jsonDatasets) {
+		t.Errorf("datasets: text %q, json %q (%v)", textDatasets, jsonDatasets, err)
+	}
+
+	textPools, _ := text.PoolStatuses()
+	jsonPools, err := js.PoolStatuses()
+	if err != nil {
+		t.Fatalf("JSON PoolStatuses: %v", err)
+	}
+	if len(jsonPools) != 1 || jsonPools[0].Raw != textPools[0].Raw {
+		t.Fatalf("pools = %+v", jsonPools)
+	}
+	tp, jp := textPools[0], jsonPools[0]
+	if tp.Name != jp.Name || tp.State != jp.State || tp.Errors != jp.Errors ||
+		!reflect.DeepEqual(tp.Config, jp.Config) {
+		t.Errorf("pool differs:\ntext %+v\njson %+v", tp, jp)
+	}
+	if want := "scrub repaired 0B with 0 errors on Sun Feb  8 00:36:32 2026"; jp.Scan != want {
+		t.Errorf("scan = %q, want %q", jp.Scan, want)
+	}
+
+	for _, c := range r.Calls() {
+		if strings.HasPrefix(c, "zfs list -t") || strings.HasPrefix(c, "zpool list") {
+			t.Errorf("text command %q used although JSON is supported", c)
+		}
+	}
+	if n := strings.Count(strings.Join(r.Calls(), "\n"), "zfs version -j"); n != 1 {
+		t.Errorf("JSON support detected %d times, want once", n)
+	}
+}
+
+func TestJSONPoolProblems(t *testing.T) {
+	r := zfstest.New()
+	r.Set("zfs version -j", zfstest.Response{Stdout: "{}"})
+	r.Set("zpool status -j -p", zfstest.Response{Stdout: `{
+  "pools": {
+    "tank": {
+      "state": "DEGRADED",
+      "status": "One or more devices are faulted.",
+      "action": "Replace the faulted device.",
+      "error_count": 2,
+      "scan_stats": {"function": "RESILVER", "state": "SCANNING",
+        "start_time": "Mon Feb  9 11:00:00 2026", "examined": "250", "to_examine": "1000"},
+      "vdevs": {"tank": {"state": "DEGRADED", "vdevs": {
+        "mirror-0": {"state": "DEGRADED", "vdevs": {
+          "sda": {"state": "ONLINE", "read_errors": "0", "write_errors": "0", "checksum_errors": "0"},
+          "sdb": {"state": "FAULTED", "read_errors": "3", "write_errors": "120", "checksum_errors": "0"}
+        }}
+      }}},
+      "spares": {"sdc": {"state": "AVAIL"}}
+    }
+  }
+}`})
+
+	pools, err := zfs.NewClient(r).PoolStatuses()
+	if err != nil {
+		t.Fatalf("PoolStatuses: %v", err)
+	}
+	p := pools[0]
+	if p.Errors != "2 data errors, use '-v' for a list" {
+		t.Errorf("errors = %q", p.Errors)
+	}
+	if p.Scan != "resilver in progress since Mon Feb  9 11:00:00 2026\n25.00% done" {
+		t.Errorf("scan = %q", p.Scan)
+	}
+	if len(p.Config) != 2 || p.Config[1].Type != "spares" {
+		t.Errorf("config = %+v", p.Config)
+	}
+	faulted := p.FaultedDevices()
+	if len(faulted) != 1 || faulted[0].Describe() != "sdb FAULTED (read 3, write 120, cksum 0)" {
+		t.Errorf("faulted = %+v", faulted)
+	}
+}
+
+func TestJSONNeverScanned(t *testing.T) {
+	r := zfstest.New()
+	r.Set("zfs version -j", zfstest.Response{Stdout: "{}"})
+	r.Set("zpool status -j -p", zfstest.Response{Stdout: `{
+  "pools": {
+    "fresh": {"state": "ONLINE", "error_count": "0",
+      "scan_stats": {"function": "NONE", "state": "NONE", "start_time": "-", "end_time": "-"},
+      "vdevs": {"fresh": {"state": "ONLINE"}}},
+    "old": {"state": "ONLINE", "error_count": "0", "vdevs": {"old": {"state": "ONLINE"}}}
+  }
+}`})
+
+	pools, err := zfs.NewClient(r).PoolStatuses()
+	if err != nil {
+		t.Fatalf("PoolStatuses: %v", err)
+	}
+	for _, p := range pools {
+		if p.Scan != "none requested" {
+			t.Errorf("%s: scan = %q, want %q", p.Name, p.Scan, "none requested")
+		}
+	}
+}
+
+func TestJSONEpochScanTimes(t *testing.T) {
+	r := zfstest.New()
+	r.Set("zfs version -j", zfstest.Response{Stdout: "{}"})
+	r.Set("zpool status -j -p", zfstest.Response{Stdout: `{
+  "pools": {
+    "tank": {"state": "ONLINE", "error_count": "0",
+      "scan_stats": {"function": "SCRUB", "state": "FINISHED",
+        "start_time": "1770510241", "end_time": 1770510992,
+        "processed": "1048576", "errors": "0"},
+      "vdevs": {"tank": {"state": "ONLINE"}}}
+  }
+}`})
+
+	pools, err := zfs.NewClient(r).PoolStatuses()
+	if err != nil {
+		t.Fatalf("PoolStatuses: %v", err)
+	}
+	scan := zfs.ParseScan(pools[0].Scan)
+	if scan.State != zfs.ScanFinished || scan.Repaired != 1<<20 {
+		t.Errorf("scan = %+v (%q)", scan, pools[0].Scan)
+	}
+	if !scan.End.Equal(time.Unix(1770510992, 0)) {
+		t.Errorf("End = %v, want %v (%q)", scan.End, time.Unix(1770510992, 0), pools[0].Scan)
+	}
+}
+
+func TestJSONScrubProgress(t *testing.T) {
+	const passStart = 1770510241
+	tests := []struct {
+		name  string
+		stats string
+		want  zfs.ScanInfo
+	}{
+		{
+			name: "running",
+			stats: `{"function": "SCRUB", "state": "SCANNING", "start_time": "1770510241", "end_time": "-",
+				"to_examine": "4294967296", "examined": "2147483648", "skipped": "0", "issued": "1073741824",
+				"processed": "1048576", "errors": "0", "pass_start": "1770510241", "pass_exam": "2147483648",
+				"issued_bytes_per_scan": "1073741824", "scrub_pause": "-", "scrub_spent_paused": "0"}`,
+			want: zfs.ScanInfo{Function: "scrub", State: zfs.ScanInProgress, Start: time.Unix(passStart, 0),
+				Repaired: 1 << 20, Percent: 25, Rate: "10M/s", ToGo: "00:05:00"},
+		},
+		{
+			name: "paused",
+			stats: `{"function": "SCRUB", "state": "SCANNING", "start_time": "1770510241", "end_time": "-",
+				"to_examine": "4294967296", "examined": "2147483648", "skipped": "0", "issued": "1073741824",
+				"processed": "0", "errors": "0", "pass_start": "1770510241", "pass_exam": "2147483648",
+				"issued_bytes_per_scan": "1073741824", "scrub_pause": "1770510291", "scrub_spent_paused": "0"}`,
+			want: zfs.ScanInfo{Function: "scrub", State: zfs.ScanPaused, Start: time.Unix(passStart, 0),
+				End: time.Unix(passStart+50, 0), Percent: 25},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := zfstest.New()
+			r.Set("zfs version -j", zfstest.Response{Stdout: "{}"})
+			r.Set("zpool status -j -p", zfstest.Response{Stdout: `{"pools": {"tank": {"state": "ONLINE",
+				"error_count": "0", "scan_stats": ` + tt.stats + `, "vdevs": {"tank": {"state": "ONLINE"}}}}}`})
+			c := zfs.NewClient(r)
+			c.SetNow(func() time.Time { return time.Unix(passStart+100, 0) })
+
+			pools, err := c.PoolStatuses()
+			if err != nil {
+				t.Fatalf("PoolStatuses: %v", err)
+			}
+			got := zfs.ParseScan(pools[0].Scan)
+			if got.Function != tt.want.Function || got.State != tt.want.State ||
+				!got.Start.Equal(tt.want.Start) || !got.End.Equal(tt.want.End) ||
+				got.Repaired != tt.want.Repaired || got.Percent != tt.want.Percent ||
+				got.Rate != tt.want.Rate || got.ToGo != tt.want.ToGo {
+				t.Errorf("ParseScan = %+v, want %+v\nscan:\n%s", got, tt.want, pools[0].Scan)
+			}
+		})
+	}
+}
+
+func TestJSONFallsBackToText(t *testing.T) {
+	c, r := scenario(t, "healthy")
+	if _, err := c.ListSnapshots(); err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if !r.Called("zfs version -j") {
+		t.Error("JSON support was not probed")
+	}
+	for _, call := range r.Calls() {
+		if strings.Contains(call, " -j ") {
+			t.Errorf("JSON command %q used although unsupported", call)
+		}
+	}
+}
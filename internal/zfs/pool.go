@@ -0,0 +1,280 @@
+package zfs
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VdevNode is one entry of the config tree printed by zpool status: the
+// pool itself, a grouping vdev (mirror, raidz, draid, spare, replacing), a
+// section header (logs, cache, spares, special, dedup) or a device.
+type VdevNode struct {
+	Name string
+	// Type is "pool", "mirror", "raidz", "draid", "spare", "replacing",
+	// "logs", "cache", "spares", "special", "dedup" or "disk".
+	Type  string
+	State string
+	// Read, Write and Checksum are the error counters. zpool abbreviates
+	// large values (1.2K), so they can be approximate.
+	Read     uint64
+	Write    uint64
+	Checksum uint64
+	// Message is the text after the counters, e.g. "too many errors" or
+	// "was /dev/sdc".
+	Message  string
+	Children []VdevNode
+}
+
+// sectionTypes are the config headers that follow the pool's own vdevs.
+var sectionTypes = map[string]bool{
+	"logs": true, "cache": true, "spares": true, "special": true, "dedup": true,
+}
+
+// vdevType derives the type of a config entry from its name and depth.
+func vdevType(name string, depth int) string {
+	if depth == 0 {
+		if sectionTypes[name] {
+			return name
+		}
+		return "pool"
+	}
+	for _, prefix := range []string{"mirror", "raidz", "draid", "spare", "replacing"} {
+		if strings.HasPrefix(name, prefix+"-") {
+			return prefix
+		}
+	}
+	// raidz vdevs are printed as raidz1-0, raidz2-0, ...; draid as
+	// draid2:4d:8c:1s-0.
+	if strings.HasPrefix(name, "raidz") {
+		return "raidz"
+	}
+	if strings.HasPrefix(name, "draid") {
+		return "draid"
+	}
+	return "disk"
+}
+
+// healthyStates are the device states that need no attention.
+var healthyStates = map[string]bool{"ONLINE": true, "AVAIL": true, "INUSE": true}
+
+// DeviceNeedsAttention reports whether a device with the given state and
+// error counters is a problem worth alerting about.
+func DeviceNeedsAttention(state string, read, write, cksum uint64) bool {
+	return !healthyStates[state] || read+write+cksum > 0
+}
+
+// Problems returns the devices below the node (including itself) that are
+// not healthy or have reported errors, leaves only.
+func (n VdevNode) Problems() []VdevNode {
+	if len(n.Children) == 0 {
+		if n.Type == "disk" && DeviceNeedsAttention(n.State, n.Read, n.Write, n.Checksum) {
+			return []VdevNode{n}
+		}
+		return nil
+	}
+	var out []VdevNode
+	for _, c := range n.Children {
+		out = append(out, c.Problems()...)
+	}
+	return out
+}
+
+// Describe summarizes the device state for alerts, e.g.
+// "sdb FAULTED (read 3, write 120, cksum 0: too many errors)".
+func (n VdevNode) Describe() string {
+	s := fmt.Sprintf("%s %s (read %d, write %d, cksum %d", n.Name, n.State, n.Read, n.Write, n.Checksum)
+	if n.Message != "" {
+		s += ": " + n.Message
+	}
+	return s + ")"
+}
+
+// FaultedDevices returns the devices of the pool that are not healthy or
+// have reported errors.
+func (p PoolStatus) FaultedDevices() []VdevNode {
+	var out []VdevNode
+	for _, n := range p.Config {
+		out = append(out, n.Problems()...)
+	}
+	return out
+}
+
+// statusKeys are the fields of zpool status output.
+var statusKeys = map[string]bool{
+	"pool": true, "id": true, "state": true, "status": true, "action": true, "see": true,
+	"scan": true, "remove": true, "checkpoint": true, "config": true, "errors": true,
+}
+
+func (c *Client) poolDetail(pool string) (PoolStatus, error) {
+	out, err := c.runner.Output("zpool", "status", pool)
+	if err != nil {
+		return PoolStatus{}, err
+	}
+	return parsePoolStatus(string(out)), nil
+}
+
+// parsePoolStatus parses the text output of "zpool status <pool>".
+func parsePoolStatus(raw string) PoolStatus {
+	status := PoolStatus{Raw: raw}
+
+	var key string     // field the current line belongs to
+	var lines []string // lines of the current field
+	var stack []*VdevNode
+	var depths []int
+
+	flush := func() {
+		text := strings.Join(lines, "\n")
+		switch key {
+		case "pool":
+			status.Name = text
+		case "state":
+			status.State = text
+		case "status":
+			status.Status = strings.Join(lines, " ")
+		case "action":
+			status.Action = strings.Join(lines, " ")
+		case "scan":
+			status.Scan = text
+		case "errors":
+			if len(lines) > 0 {
+				status.Errors = lines[0]
+			}
+		}
+		lines = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if k, v, ok := strings.Cut(trimmed, ":"); ok && statusKeys[k] && !strings.HasPrefix(line, "\t") {
+			flush()
+			key = k
+			if v = strings.TrimSpace(v); v != "" {
+				lines = append(lines, v)
+			}
+			continue
+		}
+
+		if key != "config" {
+			if trimmed != "" {
+				lines = append(lines, trimmed)
+			}
+			continue
+		}
+
+		// Config tree: a tab, then two spaces per level.
+		if trimmed == "" || !strings.HasPrefix(line, "\t") {
+			continue
+		}
+		body := strings.TrimPrefix(line, "\t")
+		fields := strings.Fields(body)
+		if fields[0] == "NAME" && len(fields) > 1 && fields[1] == "STATE" {
+			continue
+		}
+		depth := (len(body) - len(strings.TrimLeft(body, " "))) / 2
+
+		node := VdevNode{Name: fields[0], Type: vdevType(fields[0], depth)}
+		if len(fields) > 1 {
+			node.State = fields[1]
+		}
+		read, okRead := parseErrorCount(safeField(fields, 2))
+		write, okWrite := parseErrorCount(safeField(fields, 3))
+		cksum, okCksum := parseErrorCount(safeField(fields, 4))
+		if okRead && okWrite && okCksum {
+			node.Read, node.Write, node.Checksum = read, write, cksum
+			node.Message = strings.Join(fields[5:], " ")
+		} else if len(fields) > 2 {
+			// Spares print a note instead of counters ("INUSE currently in use").
+			node.Message = strings.Join(fields[2:], " ")
+		}
+
+		for len(depths) > 0 && depths[len(depths)-1] >= depth {
+			stack = stack[:len(stack)-1]
+			depths = depths[:len(depths)-1]
+		}
+		if len(stack) == 0 {
+			status.Config = append(status.Config, node)
+			stack = append(stack, &status.Config[len(status.Config)-1])
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, &parent.Children[len(parent.Children)-1])
+		}
+		depths = append(depths, depth)
+	}
+	flush()
+	return status
+}
+
+func safeField(fields []string, i int) string {
+	if i < len(fields) {
+		return fields[i]
+	}
+	return ""
+}
+
+// parseErrorCount parses a zpool error counter, which is abbreviated with
+// binary suffixes for large values (e.g. "1.2K").
+func parseErrorCount(s string) (uint64, bool) {
+	mult := 1.0
+	if n := len(s); n > 0 {
+		if i := strings.IndexByte("KMGTPE", s[n-1]); i >= 0 {
+			for range i + 1 {
+				mult *= 1024
+			}
+			s = s[:n-1]
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, false
+	}
+	return uint64(v * mult), true
+}
+
+// PoolUsage is the space accounting of a pool reported by zpool list.
+type PoolUsage struct {
+	Name      string
+	Size      uint64
+	Allocated uint64
+	Free      uint64
+	// Capacity is the allocated share of the pool in percent.
+	Capacity int
+	// Fragmentation is the free space fragmentation in percent, 0 when
+	// the pool does not report it.
+	Fragmentation int
+	// DedupRatio is the deduplication ratio, e.g. 1.00 without dedup.
+	DedupRatio float64
+}
+
+// PoolUsages returns the space accounting of every pool.
+func (c *Client) PoolUsages() ([]PoolUsage, error) {
+	out, err := c.runner.Output("zpool", "list", "-H", "-p", "-o",
+		"name,size,allocated,free,capacity,fragmentation,dedupratio")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pool usage: %w", err)
+	}
+	var usages []PoolUsage
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 7 {
+			continue
+		}
+		u := PoolUsage{Name: fields[0]}
+		u.Size, _ = parseUint(fields[1])
+		u.Allocated, _ = parseUint(fields[2])
+		u.Free, _ = parseUint(fields[3])
+		// Older releases print percentages and the ratio with their unit
+		// even with -p; fragmentation is "-" for pools without spacemaps.
+		u.Capacity, _ = strconv.Atoi(strings.TrimSuffix(fields[4], "%"))
+		u.Fragmentation, _ = strconv.Atoi(strings.TrimSuffix(fields[5], "%"))
+		u.DedupRatio, _ = strconv.ParseFloat(strings.TrimSuffix(fields[6], "x"), 64)
+		usages = append(usages, u)
+	}
+	return usages, nil
+}
@@ -0,0 +1,168 @@
+package zfs_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pbek/zfsguard/internal/zfs"
+	"github.com/pbek/zfsguard/internal/zfs/zfstest"
+)
+
+const complexStatus = `  pool: big
+ state: DEGRADED
+status: One or more devices could not be used because the label is missing or
+	invalid.  Sufficient replicas exist for the pool to continue
+	functioning in a degraded state.
+action: Replace the device using 'zpool replace'.
+   see: https://openzfs.github.io/openzfs-docs/msg/ZFS-8000-4J
+  scan: scrub in progress since Sun Feb  8 00:24:01 2026
+	1.23T / 4.56T scanned at 512M/s, 1.01T / 4.56T issued at 420M/s
+	0B repaired, 22.15% done, 02:27:12 to go
+config:
+
+	NAME                      STATE     READ WRITE CKSUM
+	big                       DEGRADED     0     0     0
+	  raidz2-0                DEGRADED     0     0     0
+	    sda                   ONLINE       0     0     0
+	    sdb                   ONLINE       0     0  1.2K
+	    spare-2               DEGRADED     0     0     0
+	      8157136287395921812  UNAVAIL      0     0     0  was /dev/sdc1
+	      sdf                 ONLINE       0     0     0
+	logs
+	  mirror-1                ONLINE       0     0     0
+	    nvme0n1p1             ONLINE       0     0     0
+	    nvme1n1p1             ONLINE       0     0     0
+	special
+	  mirror-2                ONLINE       0     0     0
+	    nvme0n1p2             ONLINE       0     0     0
+	    nvme1n1p2             ONLINE       0     0     0
+	cache
+	  nvme2n1                 ONLINE       0     0     0
+	spares
+	  sdf                     INUSE     currently in use
+	  sdg                     AVAIL
+
+errors: No known data errors
+`
+
+func TestPoolStatusVdevTree(t *testing.T) {
+	r := zfstest.New()
+	r.Set("zpool list -H -o name,health", zfstest.Response{Stdout: "big\tDEGRADED\n"})
+	r.Set("zpool status big", zfstest.Response{Stdout: complexStatus})
+
+	pools, err := zfs.NewClient(r).PoolStatuses()
+	if err != nil {
+		t.Fatalf("PoolStatuses: %v", err)
+	}
+	p := pools[0]
+
+	if !strings.HasPrefix(p.Status, "One or more devices could not be used") ||
+		!strings.HasSuffix(p.Status, "in a degraded state.") {
+		t.Errorf("status = %q", p.Status)
+	}
+	if p.Action != "Replace the device using 'zpool replace'." {
+		t.Errorf("action = %q", p.Action)
+	}
+	if want := "0B repaired, 22.15% done, 02:27:12 to go"; !strings.HasSuffix(p.Scan, want) ||
+		strings.Count(p.Scan, "\n") != 2 {
+		t.Errorf("scan = %q", p.Scan)
+	}
+	if p.Errors != "No known data errors" {
+		t.Errorf("errors = %q", p.Errors)
+	}
+
+	var got []string
+	var walk func(n zfs.VdevNode, depth int)
+	walk = func(n zfs.VdevNode, depth int) {
+		got = append(got, strings.Repeat(" ", depth)+n.Type+":"+n.Name+":"+n.State)
+		for _, c := range n.Children {
+			walk(c, depth+1)
+		}
+	}
+	for _, n := range p.Config {
+		walk(n, 0)
+	}
+	want := []string{
+		"pool:big:DEGRADED",
+		" raidz:raidz2-0:DEGRADED",
+		"  disk:sda:ONLINE",
+		"  disk:sdb:ONLINE",
+		"  spare:spare-2:DEGRADED",
+		"   disk:8157136287395921812:UNAVAIL",
+		"   disk:sdf:ONLINE",
+		"logs:logs:",
+		" mirror:mirror-1:ONLINE",
+		"  disk:nvme0n1p1:ONLINE",
+		"  disk:nvme1n1p1:ONLINE",
+		"special:special:",
+		" mirror:mirror-2:ONLINE",
+		"  disk:nvme0n1p2:ONLINE",
+		"  disk:nvme1n1p2:ONLINE",
+		"cache:cache:",
+		" disk:nvme2n1:ONLINE",
+		"spares:spares:",
+		" disk:sdf:INUSE",
+		" disk:sdg:AVAIL",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("tree =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if spare := p.Config[4].Children[0]; spare.Message != "currently in use" {
+		t.Errorf("spare message = %q", spare.Message)
+	}
+
+	var problems []string
+	for _, d := range p.FaultedDevices() {
+		problems = append(problems, d.Describe())
+	}
+	wantProblems := []string{
+		"sdb ONLINE (read 0, write 0, cksum 1228)",
+		"8157136287395921812 UNAVAIL (read 0, write 0, cksum 0: was /dev/sdc1)",
+	}
+	if strings.Join(problems, "\n") != strings.Join(wantProblems, "\n") {
+		t.Errorf("problems = %q, want %q", problems, wantProblems)
+	}
+}
+
+func TestPoolStatusesDegradedTree(t *testing.T) {
+	c, _ := scenario(t, "degraded")
+	pools, err := c.PoolStatuses()
+	if err != nil {
+		t.Fatalf("PoolStatuses: %v", err)
+	}
+	p := pools[0]
+	if !strings.HasPrefix(p.Scan, "resilvered 1.21G") || !strings.HasPrefix(p.Action, "Replace the faulted device") {
+		t.Errorf("scan = %q, action = %q", p.Scan, p.Action)
+	}
+	faulted := p.FaultedDevices()
+	if len(faulted) != 1 || faulted[0].Name != "sdb" || faulted[0].Write != 120 ||
+		faulted[0].Message != "too many errors" {
+		t.Errorf("faulted = %+v", faulted)
+	}
+}
+
+func TestPoolUsages(t *testing.T) {
+	r := zfstest.New()
+	r.Set("zpool list -H -p -o name,size,allocated,free,capacity,fragmentation,dedupratio", zfstest.Response{
+		Stdout: "tank\t3985729650688\t1243225624576\t2742504026112\t31\t7\t1.25\n" +
+			"backup\t1000\t500\t500\t50%\t-\t1.00x\n",
+	})
+	usages, err := zfs.NewClient(r).PoolUsages()
+	if err != nil {
+		t.Fatalf("PoolUsages: %v", err)
+	}
+	want := []zfs.PoolUsage{
+		{Name: "tank", Size: 3985729650688, Allocated: 1243225624576, Free: 2742504026112,
+			Capacity: 31, Fragmentation: 7, DedupRatio: 1.25},
+		{Name: "backup", Size: 1000, Allocated: 500, Free: 500, Capacity: 50, DedupRatio: 1},
+	}
+	if len(usages) != len(want) {
+		t.Fatalf("usages = %+v, want %+v", usages, want)
+	}
+	for i := range want {
+		if usages[i] != want[i] {
+			t.Errorf("usages[%d] = %+v, want %+v", i, usages[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package zfs
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Runner executes the external zfs, zpool and smartctl commands. It is the
+// single point where the package touches the system, so tests can replace it
+// with a scripted fake (see the zfstest package).
+type Runner interface {
+	// Output runs the command and returns its standard output.
+	Output(name string, args ...string) ([]byte, error)
+	// CombinedOutput runs the command and returns its combined standard
+	// output and standard error.
+	CombinedOutput(name string, args ...string) ([]byte, error)
+	// IsRoot reports whether commands run with root privileges. When they
+	// do not, modifying commands that fail with "permission denied" are
+	// retried via non-interactive sudo.
+	IsRoot() bool
+}
+
+// ExecRunner runs commands on the local system via os/exec.
+type ExecRunner struct{}
+
+// Output implements Runner.
+func (ExecRunner) Output(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).Output()
+}
+
+// CombinedOutput implements Runner.
+func (ExecRunner) CombinedOutput(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+// IsRoot implements Runner.
+func (ExecRunner) IsRoot() bool {
+	return os.Geteuid() == 0
+}
+
+// Client wraps a Runner and provides the ZFS, zpool and SMART operations
+// used by the TUI and the monitor.
+type Client struct {
+	runner Runner
+	now    func() time.Time // the clock for scan rates, see scanJSON.summary
+
+	jsonOnce sync.Once
+	jsonOK   bool // whether zfs and zpool support -j, see jsonSupported
+}
+
+// NewClient creates a Client that executes commands through r.
+// A nil Runner falls back to ExecRunner.
+func NewClient(r Runner) *Client {
+	if r == nil {
+		r = ExecRunner{}
+	}
+	return &Client{runner: r, now: time.Now}
+}
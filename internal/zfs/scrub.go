@@ -0,0 +1,143 @@
+package zfs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scan states of ScanInfo.
+const (
+	ScanFinished   = "finished"
+	ScanInProgress = "in progress"
+	ScanPaused     = "paused"
+	ScanCanceled   = "canceled"
+)
+
+// ScanInfo is the last or running scrub or resilver of a pool, parsed from
+// the "scan:" text of zpool status.
+type ScanInfo struct {
+	// Function is "scrub" or "resilver"; empty when the pool has never been
+	// scanned ("none requested") or the text is not understood.
+	Function string
+	// State is ScanFinished, ScanInProgress, ScanPaused or ScanCanceled.
+	State string
+	// Start is when a running or paused scan started; End is when a
+	// finished or canceled scan ended, or when a paused one was paused.
+	Start time.Time
+	End   time.Time
+	// Repaired is the data repaired by a scrub or resilvered, in bytes.
+	Repaired uint64
+	// Errors is the number of errors a finished scan ended with.
+	Errors uint64
+	// Percent, Rate and ToGo describe the progress of a running scan,
+	// e.g. 22.15, "420M/s" and "02:27:12". Rate and ToGo may be empty.
+	Percent float64
+	Rate    string
+	ToGo    string
+}
+
+// Active reports whether the scan is running or paused.
+func (s ScanInfo) Active() bool {
+	return s.State == ScanInProgress || s.State == ScanPaused
+}
+
+var (
+	scanFinishedRe = regexp.MustCompile(`^(?:scrub repaired|(resilver)ed) (\S+)(?: in .+?)? with (\d+) errors on (.+)$`)
+	scanStateRe    = regexp.MustCompile(`^(scrub|resilver) (in progress since|paused since|canceled on) (.+)$`)
+	scanStartedRe  = regexp.MustCompile(`^(?:scrub|resilver) started on (.+)$`)
+	scanDoneRe     = regexp.MustCompile(`(?:(\S+) (?:repaired|resilvered), )?([\d.]+)% done(?:, (.+) to go)?`)
+	scanRateRe     = regexp.MustCompile(`(?:issued|scanned) at (\S+)$`)
+)
+
+// ParseScan parses the scan text of a pool (PoolStatus.Scan), e.g.
+// "scrub repaired 0B in 00:12:31 with 0 errors on Sun Feb  8 00:36:32 2026"
+// or a running scrub with its progress lines.
+func ParseScan(scan string) ScanInfo {
+	var info ScanInfo
+	lines := strings.Split(strings.TrimSpace(scan), "\n")
+	first := strings.Join(strings.Fields(lines[0]), " ")
+
+	if m := scanFinishedRe.FindStringSubmatch(first); m != nil {
+		info.Function = "scrub"
+		if m[1] != "" {
+			info.Function = "resilver"
+		}
+		info.State = ScanFinished
+		info.Repaired, _ = parseSize(m[2])
+		info.Errors, _ = strconv.ParseUint(m[3], 10, 64)
+		info.End = parseScanTime(m[4])
+		return info
+	}
+	m := scanStateRe.FindStringSubmatch(first)
+	if m == nil {
+		return info
+	}
+	info.Function = m[1]
+	switch m[2] {
+	case "in progress since":
+		info.State = ScanInProgress
+		info.Start = parseScanTime(m[3])
+	case "paused since":
+		info.State = ScanPaused
+		info.End = parseScanTime(m[3])
+	case "canceled on":
+		info.State = ScanCanceled
+		info.End = parseScanTime(m[3])
+		return info
+	}
+
+	for _, line := range lines[1:] {
+		line = strings.Join(strings.Fields(line), " ")
+		if m := scanStartedRe.FindStringSubmatch(line); m != nil {
+			info.Start = parseScanTime(m[1])
+		}
+		// "1.23T / 4.56T scanned at 512M/s, 1.01T / 4.56T issued at 420M/s":
+		// the issue rate is the one that matters for the remaining time.
+		for _, part := range strings.Split(line, ", ") {
+			if m := scanRateRe.FindStringSubmatch(part); m != nil {
+				info.Rate = m[1]
+			}
+		}
+		if m := scanDoneRe.FindStringSubmatch(line); m != nil {
+			if m[1] != "" {
+				info.Repaired, _ = parseSize(m[1])
+			}
+			info.Percent, _ = strconv.ParseFloat(m[2], 64)
+			info.ToGo = m[3]
+		}
+	}
+	return info
+}
+
+// parseScanTime parses a time printed by zpool status, e.g.
+// "Sun Feb  8 00:36:32 2026", in local time. It is zero if not understood.
+func parseScanTime(s string) time.Time {
+	t, err := time.ParseInLocation("Mon Jan 2 15:04:05 2006", strings.Join(strings.Fields(s), " "), time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// parseSize parses a size printed by zpool status, e.g. "0B" or "1.21G".
+func parseSize(s string) (uint64, bool) {
+	return parseErrorCount(strings.TrimSuffix(s, "B"))
+}
+
+// StartScrub starts a scrub of the pool, or resumes a paused one.
+func (c *Client) StartScrub(pool string) error {
+	return c.runPrivilegedCommand("zpool", fmt.Sprintf("start scrub of %q", pool), "scrub", pool)
+}
+
+// PauseScrub pauses the running scrub of the pool; StartScrub resumes it.
+func (c *Client) PauseScrub(pool string) error {
+	return c.runPrivilegedCommand("zpool", fmt.Sprintf("pause scrub of %q", pool), "scrub", "-p", pool)
+}
+
+// CancelScrub cancels the running or paused scrub of the pool.
+func (c *Client) CancelScrub(pool string) error {
+	return c.runPrivilegedCommand("zpool", fmt.Sprintf("cancel scrub of %q", pool), "scrub", "-s", pool)
+}
@@ -0,0 +1,100 @@
+package zfs_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/zfs"
+	"github.com/pbek/zfsguard/internal/zfs/zfstest"
+)
+
+func TestParseScan(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	tests := []struct {
+		name string
+		scan string
+		want zfs.ScanInfo
+	}{
+		{"never", "none requested", zfs.ScanInfo{}},
+		{"scrub finished",
+			"scrub repaired 0B in 00:12:31 with 0 errors on Sun Feb  8 00:36:32 2026",
+			zfs.ScanInfo{Function: "scrub", State: zfs.ScanFinished, End: at("2026-02-08 00:36:32")}},
+		{"scrub repaired",
+			"scrub repaired 1.50M in 1 days 02:03:04 with 3 errors on Sun Feb  8 00:36:32 2026",
+			zfs.ScanInfo{Function: "scrub", State: zfs.ScanFinished, End: at("2026-02-08 00:36:32"),
+				Repaired: 1572864, Errors: 3}},
+		{"resilver finished",
+			"resilvered 1.21G in 00:03:12 with 0 errors on Mon Feb  9 11:02:44 2026",
+			zfs.ScanInfo{Function: "resilver", State: zfs.ScanFinished, End: at("2026-02-09 11:02:44"),
+				Repaired: 1299227607}},
+		{"JSON summary finished",
+			"scrub repaired 0B with 0 errors on Sun Feb  8 00:36:32 2026",
+			zfs.ScanInfo{Function: "scrub", State: zfs.ScanFinished, End: at("2026-02-08 00:36:32")}},
+		{"scrub in progress",
+			"scrub in progress since Sun Feb  8 00:24:01 2026\n" +
+				"\t1.23T / 4.56T scanned at 512M/s, 1.01T / 4.56T issued at 420M/s\n" +
+				"\t4K repaired, 22.15% done, 02:27:12 to go",
+			zfs.ScanInfo{Function: "scrub", State: zfs.ScanInProgress, Start: at("2026-02-08 00:24:01"),
+				Repaired: 4096, Percent: 22.15, Rate: "420M/s", ToGo: "02:27:12"}},
+		{"resilver in progress",
+			"resilver in progress since Mon Feb  9 10:59:32 2026\n" +
+				"\t812M / 1.21G scanned, 640M / 1.21G issued\n" +
+				"\t640M resilvered, 51.65% done, no estimated completion time",
+			zfs.ScanInfo{Function: "resilver", State: zfs.ScanInProgress, Start: at("2026-02-09 10:59:32"),
+				Repaired: 671088640, Percent: 51.65}},
+		{"JSON summary in progress",
+			"scrub in progress since Sun Feb  8 00:24:01 2026\n22.15% done",
+			zfs.ScanInfo{Function: "scrub", State: zfs.ScanInProgress, Start: at("2026-02-08 00:24:01"),
+				Percent: 22.15}},
+		{"scrub paused",
+			"scrub paused since Mon Feb  9 08:00:00 2026\n" +
+				"\tscrub started on Sun Feb  8 00:24:01 2026\n" +
+				"\t1.23T / 4.56T scanned, 1.01T / 4.56T issued, 0B repaired, 22.15% done",
+			zfs.ScanInfo{Function: "scrub", State: zfs.ScanPaused, Start: at("2026-02-08 00:24:01"),
+				End: at("2026-02-09 08:00:00"), Percent: 22.15}},
+		{"scrub canceled", "scrub canceled on Sun Feb  8 00:30:00 2026",
+			zfs.ScanInfo{Function: "scrub", State: zfs.ScanCanceled, End: at("2026-02-08 00:30:00")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := zfs.ParseScan(tt.scan); got != tt.want {
+				t.Errorf("ParseScan =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartScrubFallsBackToSudo(t *testing.T) {
+	r := zfstest.New()
+	r.Set("zpool scrub tank", zfstest.Response{Stderr: "cannot scrub tank: permission denied\n", ExitCode: 1})
+	r.Set("sudo -n zpool scrub tank", zfstest.Response{})
+	if err := zfs.NewClient(r).StartScrub("tank"); err != nil {
+		t.Fatalf("StartScrub: %v", err)
+	}
+	if !r.Called("sudo -n zpool scrub tank") {
+		t.Errorf("calls = %q", r.Calls())
+	}
+}
+
+func TestScrubControl(t *testing.T) {
+	r := zfstest.New()
+	r.SetRoot(true)
+	r.Set("zpool scrub -p tank", zfstest.Response{})
+	r.Set("zpool scrub -s tank", zfstest.Response{Stderr: "cannot cancel scrubbing tank: there is no active scrub\n", ExitCode: 1})
+	c := zfs.NewClient(r)
+
+	if err := c.PauseScrub("tank"); err != nil {
+		t.Errorf("PauseScrub: %v", err)
+	}
+	err := c.CancelScrub("tank")
+	if err == nil || !strings.Contains(err.Error(), "there is no active scrub") {
+		t.Errorf("CancelScrub = %v", err)
+	}
+}
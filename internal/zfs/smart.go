@@ -4,24 +4,80 @@ package zfs
 import (
 	"bufio"
 	"fmt"
-	"os/exec"
 	"strings"
 )
 
+// SMARTHealth is the graded health of a disk.
+type SMARTHealth string
+
+const (
+	SMARTOK       SMARTHealth = "ok"
+	SMARTWarning  SMARTHealth = "warning"
+	SMARTCritical SMARTHealth = "critical"
+)
+
+func (h SMARTHealth) rank() int {
+	switch h {
+	case SMARTOK:
+		return 0
+	case SMARTWarning:
+		return 1
+	}
+	return 2
+}
+
+// Worse reports whether h is more severe than other.
+func (h SMARTHealth) Worse(other SMARTHealth) bool {
+	return h.rank() > other.rank()
+}
+
 // SMARTStatus holds the SMART health status of a disk.
 type SMARTStatus struct {
-	Device  string
-	Healthy bool
+	Device string
+	// Health is critical when the self-assessment failed, and warning when
+	// smartctl could not determine it. Threshold rules (see Escalate) can
+	// raise it further.
+	Health  SMARTHealth
 	Summary string
-	Raw     string
+	// Issues lists the threshold rules the disk violates.
+	Issues []string
+	Raw    string
+	// Attributes are the device statistics from "smartctl -a -j". They are
+	// nil when smartctl is too old for JSON output or could not read the
+	// device.
+	Attributes *SMARTAttributes
+}
+
+// Healthy reports whether the disk needs no attention.
+func (s SMARTStatus) Healthy() bool {
+	return s.Health == SMARTOK
+}
+
+// Escalate raises the health of the disk to h, if that is worse, and records
+// why.
+func (s *SMARTStatus) Escalate(h SMARTHealth, issue string) {
+	if h.Worse(s.Health) {
+		s.Health = h
+	}
+	s.Issues = append(s.Issues, issue)
+}
+
+// Describe summarizes the disk for alerts, e.g.
+// "Device /dev/sda: PASSED; Reallocated_Sector_Ct 8 > 0 (warning)".
+func (s SMARTStatus) Describe() string {
+	text := fmt.Sprintf("Device %s: %s", s.Device, s.Summary)
+	if len(s.Issues) > 0 {
+		text += "; " + strings.Join(s.Issues, "; ")
+	}
+	return text
 }
 
 // CheckSMART runs smartctl on the given devices and returns their health status.
 // If devices is empty, it attempts to auto-detect devices.
-func CheckSMART(devices []string) ([]SMARTStatus, error) {
+func (c *Client) CheckSMART(devices []string) ([]SMARTStatus, error) {
 	if len(devices) == 0 {
 		var err error
-		devices, err = detectDevices()
+		devices, err = c.detectDevices()
 		if err != nil {
 			return nil, fmt.Errorf("failed to detect devices: %w", err)
 		}
@@ -29,23 +85,23 @@ func CheckSMART(devices []string) ([]SMARTStatus, error) {
 
 	var statuses []SMARTStatus
 	for _, dev := range devices {
-		status := checkDevice(dev)
+		status := c.checkDevice(dev)
 		statuses = append(statuses, status)
 	}
 	return statuses, nil
 }
 
 // CheckSMARTErrors checks all detected disks and returns a summary of any issues.
-func CheckSMARTErrors(devices []string) (hasErrors bool, summary string, err error) {
-	statuses, err := CheckSMART(devices)
+func (c *Client) CheckSMARTErrors(devices []string) (hasErrors bool, summary string, err error) {
+	statuses, err := c.CheckSMART(devices)
 	if err != nil {
 		return false, "", err
 	}
 
 	var issues []string
 	for _, s := range statuses {
-		if !s.Healthy {
-			issues = append(issues, fmt.Sprintf("Device %s: %s", s.Device, s.Summary))
+		if !s.Healthy() {
+			issues = append(issues, s.Describe())
 		}
 	}
 
@@ -55,9 +111,8 @@ func CheckSMARTErrors(devices []string) (hasErrors bool, summary string, err err
 	return false, "All disks healthy", nil
 }
 
-func detectDevices() ([]string, error) {
-	cmd := exec.Command("smartctl", "--scan")
-	out, err := cmd.Output()
+func (c *Client) detectDevices() ([]string, error) {
+	out, err := c.runner.Output("smartctl", "--scan")
 	if err != nil {
 		return nil, err
 	}
@@ -77,9 +132,21 @@ func detectDevices() ([]string, error) {
 	return devices, nil
 }
 
-func checkDevice(device string) SMARTStatus {
-	cmd := exec.Command("smartctl", "-H", device)
-	out, err := cmd.CombinedOutput()
+func (c *Client) checkDevice(device string) SMARTStatus {
+	// smartctl 7.0 and later print JSON with -j. Its exit status is a bit
+	// mask that is non-zero for many benign reasons (e.g. old errors in the
+	// device log), so the JSON content decides and err is ignored here.
+	out, _ := c.runner.Output("smartctl", "-a", "-j", device)
+	if status, ok := parseSMARTJSON(device, out); ok {
+		return status
+	}
+	return c.checkDeviceText(device)
+}
+
+// checkDeviceText is the health check for smartctl releases without JSON
+// output.
+func (c *Client) checkDeviceText(device string) SMARTStatus {
+	out, err := c.runner.CombinedOutput("smartctl", "-H", device)
 	raw := string(out)
 
 	status := SMARTStatus{
@@ -89,7 +156,7 @@ func checkDevice(device string) SMARTStatus {
 
 	if err != nil {
 		// smartctl returns non-zero for unhealthy disks
-		status.Healthy = false
+		status.Health = SMARTWarning
 		status.Summary = "smartctl returned an error"
 	}
 
@@ -98,20 +165,20 @@ func checkDevice(device string) SMARTStatus {
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if strings.Contains(line, "PASSED") || strings.Contains(line, "OK") {
-			status.Healthy = true
+			status.Health = SMARTOK
 			status.Summary = "PASSED"
 			return status
 		}
 		if strings.Contains(line, "FAILED") {
-			status.Healthy = false
+			status.Health = SMARTCritical
 			status.Summary = "FAILED - " + line
 			return status
 		}
 	}
 
 	if status.Summary == "" {
+		status.Health = SMARTWarning
 		status.Summary = "Unable to determine health status"
-		status.Healthy = true // assume healthy if we can't determine
 	}
 	return status
 }
@@ -0,0 +1,211 @@
+package zfs_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pbek/zfsguard/internal/zfs"
+	"github.com/pbek/zfsguard/internal/zfs/zfstest"
+)
+
+func TestCheckSMART(t *testing.T) {
+	const (
+		ok       = zfs.SMARTOK
+		warning  = zfs.SMARTWarning
+		critical = zfs.SMARTCritical
+	)
+	tests := []struct {
+		scenario string
+		health   map[string]zfs.SMARTHealth
+	}{
+		{"healthy", map[string]zfs.SMARTHealth{"/dev/sda": ok, "/dev/sdb": ok}},
+		{"smart-failed", map[string]zfs.SMARTHealth{"/dev/sda": ok, "/dev/sdb": critical}},
+		{"permission-denied", map[string]zfs.SMARTHealth{"/dev/sda": warning}},
+		// Without threshold rules worn disks that pass the self-assessment are ok.
+		{"smart-worn", map[string]zfs.SMARTHealth{"/dev/sda": ok, "/dev/nvme0": ok, "/dev/sdc": ok}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scenario, func(t *testing.T) {
+			c, _ := scenario(t, tt.scenario)
+			statuses, err := c.CheckSMART(nil)
+			if err != nil {
+				t.Fatalf("CheckSMART: %v", err)
+			}
+			if len(statuses) != len(tt.health) {
+				t.Fatalf("got %d devices, want %d", len(statuses), len(tt.health))
+			}
+			for _, s := range statuses {
+				if s.Health != tt.health[s.Device] {
+					t.Errorf("%s: Health = %v, want %v (%s)", s.Device, s.Health,
+						tt.health[s.Device], s.Summary)
+				}
+				if s.Healthy() != (s.Health == ok) {
+					t.Errorf("%s: Healthy() = %v with health %v", s.Device, s.Healthy(), s.Health)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckSMARTExplicitDevices(t *testing.T) {
+	c, r := scenario(t, "healthy")
+	statuses, err := c.CheckSMART([]string{"/dev/sdb"})
+	if err != nil {
+		t.Fatalf("CheckSMART: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Device != "/dev/sdb" {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+	if r.Called("smartctl --scan") {
+		t.Error("device scan ran although devices were configured")
+	}
+}
+
+func TestCheckSMARTErrors(t *testing.T) {
+	c, _ := scenario(t, "smart-failed")
+	hasErrors, summary, err := c.CheckSMARTErrors(nil)
+	if err != nil {
+		t.Fatalf("CheckSMARTErrors: %v", err)
+	}
+	if !hasErrors {
+		t.Fatal("hasErrors = false")
+	}
+	want := "Device /dev/sdb: FAILED - Reallocated_Sector_Ct FAILING_NOW (raw 4088)"
+	if summary != want {
+		t.Errorf("summary = %q, want %q", summary, want)
+	}
+}
+
+func TestCheckSMARTAttributes(t *testing.T) {
+	c, _ := scenario(t, "smart-worn")
+	statuses, err := c.CheckSMART(nil)
+	if err != nil {
+		t.Fatalf("CheckSMART: %v", err)
+	}
+	byDevice := map[string]*zfs.SMARTAttributes{}
+	for _, s := range statuses {
+		if s.Attributes == nil {
+			t.Fatalf("%s: no attributes", s.Device)
+		}
+		byDevice[s.Device] = s.Attributes
+	}
+
+	ata := byDevice["/dev/sda"]
+	if ata.Protocol != "ATA" || ata.Temperature != 58 || ata.PowerOnHours != 52311 {
+		t.Errorf("ATA = %+v", ata)
+	}
+	realloc, ok := ata.Attribute("Reallocated_Sector_Ct")
+	if !ok || realloc.ID != 5 || realloc.Raw != 8 || realloc.Threshold != 140 {
+		t.Errorf("Reallocated_Sector_Ct = %+v, %v", realloc, ok)
+	}
+	if pending, _ := ata.Attribute("Current_Pending_Sector"); pending.Raw != 12 {
+		t.Errorf("Current_Pending_Sector raw = %d, want 12", pending.Raw)
+	}
+	if temp, _ := ata.Attribute("Temperature_Celsius"); temp.RawString != "58 (Min/Max 20/45)" {
+		t.Errorf("Temperature_Celsius raw string = %q", temp.RawString)
+	}
+
+	nvme := byDevice["/dev/nvme0"]
+	if nvme.Protocol != "NVMe" || nvme.NVMe == nil {
+		t.Fatalf("NVMe = %+v", nvme)
+	}
+	want := zfs.NVMeHealth{
+		AvailableSpare:          100,
+		AvailableSpareThreshold: 10,
+		PercentageUsed:          86,
+		ErrorLogEntries:         3,
+		UnsafeShutdowns:         41,
+	}
+	if *nvme.NVMe != want {
+		t.Errorf("NVMe health = %+v, want %+v", *nvme.NVMe, want)
+	}
+	if nvme.Temperature != 47 || nvme.PowerOnHours != 17520 {
+		t.Errorf("NVMe temperature %d, power on %d", nvme.Temperature, nvme.PowerOnHours)
+	}
+
+	scsi := byDevice["/dev/sdc"]
+	wantSCSI := zfs.SCSIErrorCounters{VerifyUncorrected: 1, GrownDefects: 2}
+	if scsi.Protocol != "SCSI" || scsi.SCSI == nil || *scsi.SCSI != wantSCSI {
+		t.Errorf("SCSI = %+v, counters %+v", scsi, scsi.SCSI)
+	}
+}
+
+func TestCheckSMARTPermissionDenied(t *testing.T) {
+	c, _ := scenario(t, "permission-denied")
+	statuses, err := c.CheckSMART(nil)
+	if err != nil {
+		t.Fatalf("CheckSMART: %v", err)
+	}
+	s := statuses[0]
+	want := "smartctl returned an error: Smartctl open device: /dev/sda failed: Permission denied"
+	if s.Health != zfs.SMARTWarning || s.Summary != want || s.Attributes != nil {
+		t.Errorf("status = %+v, want warning with summary %q", s, want)
+	}
+}
+
+func TestCheckSMARTTextFallback(t *testing.T) {
+	// smartctl before 7.0 rejects -j and only the -H check is available.
+	r := zfstest.New()
+	r.Set("smartctl -a -j /dev/sda", zfstest.Response{
+		Stdout:   "smartctl 6.6 2017-11-05 r4594 [x86_64-linux-4.19.0] (local build)\n=======> UNRECOGNIZED OPTION: j\n",
+		ExitCode: 1,
+	})
+	r.Set("smartctl -H /dev/sda", zfstest.Response{
+		Stdout: "=== START OF READ SMART DATA SECTION ===\nSMART overall-health self-assessment test result: FAILED!\n",
+	})
+	statuses, err := zfs.NewClient(r).CheckSMART([]string{"/dev/sda"})
+	if err != nil {
+		t.Fatalf("CheckSMART: %v", err)
+	}
+	s := statuses[0]
+	if s.Health != zfs.SMARTCritical || s.Attributes != nil || !strings.HasPrefix(s.Summary, "FAILED - SMART overall-health") {
+		t.Errorf("status = %+v", s)
+	}
+}
+
+func TestCheckSMARTUndetermined(t *testing.T) {
+	// A device without SMART support: smartctl answers, but has no verdict.
+	r := zfstest.New()
+	r.Set("smartctl -a -j /dev/sda", zfstest.Response{
+		Stdout: `{"smartctl": {"version": [7, 4], "exit_status": 0}, "device": {"protocol": "ATA"}}`,
+	})
+	statuses, err := zfs.NewClient(r).CheckSMART([]string{"/dev/sda"})
+	if err != nil {
+		t.Fatalf("CheckSMART: %v", err)
+	}
+	if s := statuses[0]; s.Healthy() || s.Summary != "Unable to determine health status" {
+		t.Errorf("status = %+v, want not healthy", s)
+	}
+}
+
+func TestSMARTAttributesValue(t *testing.T) {
+	c, _ := scenario(t, "smart-worn")
+	statuses, err := c.CheckSMART(nil)
+	if err != nil {
+		t.Fatalf("CheckSMART: %v", err)
+	}
+	tests := []struct {
+		device, name string
+		want         float64
+		ok           bool
+	}{
+		{"/dev/sda", "Reallocated_Sector_Ct", 8, true},
+		{"/dev/sda", "temperature", 58, true},
+		{"/dev/sda", "percentage_used", 0, false},
+		{"/dev/nvme0", "percentage_used", 86, true},
+		{"/dev/nvme0", "Reallocated_Sector_Ct", 0, false},
+		{"/dev/sdc", "grown_defects", 2, true},
+		{"/dev/sdc", "power_on_hours", 40211, true},
+	}
+	for _, tt := range tests {
+		for _, s := range statuses {
+			if s.Device != tt.device {
+				continue
+			}
+			got, ok := s.Attributes.Value(tt.name)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("%s %s = %v, %v; want %v, %v", tt.device, tt.name, got, ok, tt.want, tt.ok)
+			}
+		}
+	}
+}
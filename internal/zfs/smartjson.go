@@ -0,0 +1,289 @@
+package zfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SMARTAttributes are the device statistics reported by "smartctl -a -j".
+// Which of ATA, NVMe and SCSI is set depends on Protocol.
+type SMARTAttributes struct {
+	// Protocol is "ATA", "NVMe" or "SCSI".
+	Protocol string
+	Model    string
+	Serial   string
+	// Temperature is the current temperature in °C, 0 if not reported.
+	Temperature  int
+	PowerOnHours uint64
+
+	ATA  []ATAAttribute
+	NVMe *NVMeHealth
+	SCSI *SCSIErrorCounters
+}
+
+// ATAAttribute is one row of the ATA SMART attribute table.
+type ATAAttribute struct {
+	ID   int
+	Name string
+	// Value, Worst and Threshold are the normalized values; the attribute
+	// fails when Value drops to Threshold or below.
+	Value     int
+	Worst     int
+	Threshold int
+	// WhenFailed is "FAILING_NOW", "In_the_past" or empty.
+	WhenFailed string
+	Raw        uint64
+	// RawString is the raw value as smartctl prints it, e.g. "34 (Min/Max 20/45)".
+	RawString string
+}
+
+// NVMeHealth is the NVMe SMART / health information log.
+type NVMeHealth struct {
+	// CriticalWarning is a bit mask; any set bit is a problem.
+	CriticalWarning         int
+	AvailableSpare          int // percent
+	AvailableSpareThreshold int // percent
+	PercentageUsed          int // estimated wear, can exceed 100
+	MediaErrors             uint64
+	ErrorLogEntries         uint64
+	UnsafeShutdowns         uint64
+}
+
+// SCSIErrorCounters are the uncorrected error counters of the SCSI error
+// counter log and the size of the grown defect list.
+type SCSIErrorCounters struct {
+	ReadUncorrected   uint64
+	WriteUncorrected  uint64
+	VerifyUncorrected uint64
+	GrownDefects      uint64
+}
+
+// Attribute returns the ATA attribute with the given name, e.g.
+// "Reallocated_Sector_Ct".
+func (a *SMARTAttributes) Attribute(name string) (ATAAttribute, bool) {
+	for _, attr := range a.ATA {
+		if attr.Name == name {
+			return attr, true
+		}
+	}
+	return ATAAttribute{}, false
+}
+
+// smartctlJSON is the part of the "smartctl -a -j" output zfsguard uses.
+type smartctlJSON struct {
+	Smartctl *struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	Device struct {
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelName    string `json:"model_name"`
+	SerialNumber string `json:"serial_number"`
+	SmartStatus  *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime struct {
+		Hours uint64 `json:"hours"`
+	} `json:"power_on_time"`
+	ATASmartAttributes *struct {
+		Table []struct {
+			ID         int    `json:"id"`
+			Name       string `json:"name"`
+			Value      int    `json:"value"`
+			Worst      int    `json:"worst"`
+			Thresh     int    `json:"thresh"`
+			WhenFailed string `json:"when_failed"`
+			Raw        struct {
+				Value  uint64 `json:"value"`
+				String string `json:"string"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeLog *struct {
+		CriticalWarning         int    `json:"critical_warning"`
+		Temperature             int    `json:"temperature"`
+		AvailableSpare          int    `json:"available_spare"`
+		AvailableSpareThreshold int    `json:"available_spare_threshold"`
+		PercentageUsed          int    `json:"percentage_used"`
+		PowerOnHours            uint64 `json:"power_on_hours"`
+		UnsafeShutdowns         uint64 `json:"unsafe_shutdowns"`
+		MediaErrors             uint64 `json:"media_errors"`
+		NumErrLogEntries        uint64 `json:"num_err_log_entries"`
+	} `json:"nvme_smart_health_information_log"`
+	SCSIErrorCounterLog *struct {
+		Read   scsiCounterJSON `json:"read"`
+		Write  scsiCounterJSON `json:"write"`
+		Verify scsiCounterJSON `json:"verify"`
+	} `json:"scsi_error_counter_log"`
+	SCSIGrownDefectList *uint64 `json:"scsi_grown_defect_list"`
+}
+
+type scsiCounterJSON struct {
+	TotalUncorrectedErrors uint64 `json:"total_uncorrected_errors"`
+}
+
+// parseSMARTJSON parses the output of "smartctl -a -j". ok is false when
+// the output is not smartctl JSON, i.e. smartctl is older than 7.0.
+func parseSMARTJSON(device string, data []byte) (status SMARTStatus, ok bool) {
+	var out smartctlJSON
+	if err := json.Unmarshal(data, &out); err != nil || out.Smartctl == nil {
+		return SMARTStatus{}, false
+	}
+
+	status = SMARTStatus{Device: device, Raw: string(data)}
+
+	if out.SmartStatus == nil {
+		// smartctl could not open or identify the device; its messages
+		// say why.
+		var errs []string
+		for _, m := range out.Smartctl.Messages {
+			if m.Severity == "error" {
+				errs = append(errs, m.String)
+			}
+		}
+		if len(errs) > 0 || out.Smartctl.ExitStatus != 0 {
+			// Keep what smartctl said rather than the JSON around it.
+			status.Raw = strings.Join(errs, "\n")
+			status.Health = SMARTWarning
+			status.Summary = "smartctl returned an error"
+			if len(errs) > 0 {
+				status.Summary += ": " + strings.Join(errs, "; ")
+			}
+			return status, true
+		}
+		status.Health = SMARTWarning
+		status.Summary = "Unable to determine health status"
+	}
+
+	attrs := &SMARTAttributes{
+		Protocol:     out.Device.Protocol,
+		Model:        out.ModelName,
+		Serial:       out.SerialNumber,
+		Temperature:  out.Temperature.Current,
+		PowerOnHours: out.PowerOnTime.Hours,
+	}
+	if t := out.ATASmartAttributes; t != nil {
+		for _, a := range t.Table {
+			attrs.ATA = append(attrs.ATA, ATAAttribute{
+				ID:         a.ID,
+				Name:       a.Name,
+				Value:      a.Value,
+				Worst:      a.Worst,
+				Threshold:  a.Thresh,
+				WhenFailed: a.WhenFailed,
+				Raw:        a.Raw.Value,
+				RawString:  a.Raw.String,
+			})
+		}
+	}
+	if n := out.NVMeLog; n != nil {
+		attrs.NVMe = &NVMeHealth{
+			CriticalWarning:         n.CriticalWarning,
+			AvailableSpare:          n.AvailableSpare,
+			AvailableSpareThreshold: n.AvailableSpareThreshold,
+			PercentageUsed:          n.PercentageUsed,
+			MediaErrors:             n.MediaErrors,
+			ErrorLogEntries:         n.NumErrLogEntries,
+			UnsafeShutdowns:         n.UnsafeShutdowns,
+		}
+		if attrs.Temperature == 0 {
+			attrs.Temperature = n.Temperature
+		}
+		if attrs.PowerOnHours == 0 {
+			attrs.PowerOnHours = n.PowerOnHours
+		}
+	}
+	if l := out.SCSIErrorCounterLog; l != nil || out.SCSIGrownDefectList != nil {
+		attrs.SCSI = &SCSIErrorCounters{}
+		if l != nil {
+			attrs.SCSI.ReadUncorrected = l.Read.TotalUncorrectedErrors
+			attrs.SCSI.WriteUncorrected = l.Write.TotalUncorrectedErrors
+			attrs.SCSI.VerifyUncorrected = l.Verify.TotalUncorrectedErrors
+		}
+		if out.SCSIGrownDefectList != nil {
+			attrs.SCSI.GrownDefects = *out.SCSIGrownDefectList
+		}
+	}
+	status.Attributes = attrs
+
+	if out.SmartStatus != nil {
+		status.Health = SMARTOK
+		status.Summary = "PASSED"
+		if !out.SmartStatus.Passed {
+			status.Health = SMARTCritical
+			status.Summary = "FAILED"
+			if failing := attrs.failingAttributes(); len(failing) > 0 {
+				status.Summary += " - " + strings.Join(failing, ", ")
+			}
+		}
+	}
+	return status, true
+}
+
+// Value returns the current value of a named statistic for threshold
+// rules: an ATA attribute name (its raw value, e.g. "Reallocated_Sector_Ct"),
+// "temperature", "power_on_hours", an NVMe health log field
+// ("critical_warning", "available_spare", "percentage_used", "media_errors",
+// "error_log_entries", "unsafe_shutdowns") or a SCSI counter
+// ("read_uncorrected", "write_uncorrected", "verify_uncorrected",
+// "grown_defects"). ok is false when the disk does not report it.
+func (a *SMARTAttributes) Value(name string) (v float64, ok bool) {
+	switch name {
+	case "temperature":
+		return float64(a.Temperature), a.Temperature != 0
+	case "power_on_hours":
+		return float64(a.PowerOnHours), a.PowerOnHours != 0
+	}
+	if n := a.NVMe; n != nil {
+		switch name {
+		case "critical_warning":
+			return float64(n.CriticalWarning), true
+		case "available_spare":
+			return float64(n.AvailableSpare), true
+		case "percentage_used":
+			return float64(n.PercentageUsed), true
+		case "media_errors":
+			return float64(n.MediaErrors), true
+		case "error_log_entries":
+			return float64(n.ErrorLogEntries), true
+		case "unsafe_shutdowns":
+			return float64(n.UnsafeShutdowns), true
+		}
+	}
+	if c := a.SCSI; c != nil {
+		switch name {
+		case "read_uncorrected":
+			return float64(c.ReadUncorrected), true
+		case "write_uncorrected":
+			return float64(c.WriteUncorrected), true
+		case "verify_uncorrected":
+			return float64(c.VerifyUncorrected), true
+		case "grown_defects":
+			return float64(c.GrownDefects), true
+		}
+	}
+	if attr, ok := a.Attribute(name); ok {
+		return float64(attr.Raw), true
+	}
+	return 0, false
+}
+
+// failingAttributes describes the ATA attributes at or below their
+// threshold, e.g. "Reallocated_Sector_Ct FAILING_NOW (raw 4088)".
+func (a *SMARTAttributes) failingAttributes() []string {
+	var out []string
+	for _, attr := range a.ATA {
+		if attr.WhenFailed == "FAILING_NOW" {
+			out = append(out, fmt.Sprintf("%s FAILING_NOW (raw %s)", attr.Name, attr.RawString))
+		}
+	}
+	return out
+}
@@ -4,8 +4,7 @@ package zfs
 import (
 	"bufio"
 	"fmt"
-	"os"
-	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,8 +17,16 @@ type Snapshot struct {
 	ShortName string
 	Used      string
 	Refer     string
-	Creation  time.Time
-	Selected  bool
+	// UsedBytes and ReferBytes are the exact sizes behind Used and Refer.
+	UsedBytes  uint64
+	ReferBytes uint64
+	Creation   time.Time
+	// Clones lists datasets cloned from this snapshot. A snapshot with
+	// clones cannot be destroyed until the clones are promoted or destroyed.
+	Clones []string
+	// UserRefs is the number of user holds on the snapshot (see Holds).
+	UserRefs int
+	Selected bool
 }
 
 // PoolStatus holds the health status of a ZFS pool.
@@ -27,24 +34,37 @@ type PoolStatus struct {
 	Name   string
 	State  string
 	Errors string
+	// Status and Action are the explanation and suggested fix zpool prints
+	// for pools that need attention; Scan describes the last or running
+	// scrub or resilver.
+	Status string
+	Action string
+	Scan   string
+	// Config is the vdev tree: the pool itself, followed by the logs, cache,
+	// spares, special and dedup sections if present.
+	Config []VdevNode
 	Raw    string
 }
 
 // ListSnapshots returns all ZFS snapshots on the system.
-func ListSnapshots() ([]Snapshot, error) {
-	cmd := exec.Command(
+func (c *Client) ListSnapshots() ([]Snapshot, error) {
+	if c.jsonSupported() {
+		if snaps, err := c.listSnapshotsJSON(); err == nil {
+			return snaps, nil
+		}
+	}
+	out, err := c.runner.Output(
 		"zfs",
 		"list",
 		"-t",
 		"snapshot",
 		"-H",
 		"-o",
-		"name,used,refer,creation",
+		"name,used,refer,creation,clones,userrefs",
 		"-p",
 		"-s",
 		"creation",
 	)
-	out, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list snapshots: %w", err)
 	}
@@ -52,9 +72,13 @@ func ListSnapshots() ([]Snapshot, error) {
 }
 
 // ListDatasets returns all ZFS datasets on the system.
-func ListDatasets() ([]string, error) {
-	cmd := exec.Command("zfs", "list", "-H", "-o", "name")
-	out, err := cmd.Output()
+func (c *Client) ListDatasets() ([]string, error) {
+	if c.jsonSupported() {
+		if datasets, err := c.listDatasetsJSON(); err == nil {
+			return datasets, nil
+		}
+	}
+	out, err := c.runner.Output("zfs", "list", "-H", "-o", "name")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list datasets: %w", err)
 	}
@@ -71,56 +95,310 @@ func ListDatasets() ([]string, error) {
 }
 
 // CreateSnapshot creates a new ZFS snapshot with the given name.
-// name should be in the format "dataset@snapname".
-func CreateSnapshot(name string) error {
-	cmd := exec.Command("zfs", "snapshot", name)
-	if out, err := cmd.CombinedOutput(); err != nil {
+// name should be in the format "dataset@snapname". If recursive is set,
+// snapshots with the same name are created for all descendant datasets.
+func (c *Client) CreateSnapshot(name string, recursive bool) error {
+	args := []string{"snapshot"}
+	if recursive {
+		args = append(args, "-r")
+	}
+	args = append(args, name)
+	if out, err := c.runner.CombinedOutput("zfs", args...); err != nil {
 		return fmt.Errorf("failed to create snapshot %q: %s: %w", name, string(out), err)
 	}
 	return nil
 }
 
 // DestroySnapshot destroys a ZFS snapshot.
-func DestroySnapshot(name string) error {
-	cmd := exec.Command("zfs", "destroy", name)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		outStr := string(out)
-		if !isRoot() && strings.Contains(strings.ToLower(outStr), "permission denied") {
-			sudoCmd := exec.Command("sudo", "-n", "zfs", "destroy", name)
-			sudoOut, sudoErr := sudoCmd.CombinedOutput()
-			if sudoErr == nil {
-				return nil
-			}
-			sudoLower := strings.ToLower(string(sudoOut))
-			if strings.Contains(sudoLower, "a password is required") ||
-				strings.Contains(sudoLower, "interactive authentication is required") {
-				return fmt.Errorf(
-					"failed to destroy snapshot %q: permission denied; run zfsguard with sudo or configure NOPASSWD",
-					name,
-				)
-			} else {
-				return fmt.Errorf("failed to destroy snapshot %q: %s: %w", name, string(sudoOut), sudoErr)
-			}
-		}
-		return fmt.Errorf("failed to destroy snapshot %q: %s: %w", name, outStr, err)
-	}
-	return nil
+func (c *Client) DestroySnapshot(name string) error {
+	return c.runPrivileged(fmt.Sprintf("destroy snapshot %q", name), "destroy", name)
 }
 
 // DestroySnapshots destroys multiple ZFS snapshots.
 // Returns a map of snapshot name to error (nil if successful).
-func DestroySnapshots(names []string) map[string]error {
+func (c *Client) DestroySnapshots(names []string) map[string]error {
 	results := make(map[string]error, len(names))
 	for _, name := range names {
-		results[name] = DestroySnapshot(name)
+		results[name] = c.DestroySnapshot(name)
 	}
 	return results
 }
 
+// EstimateDestroy returns the number of bytes that destroying the given
+// snapshots would free, as reported by "zfs destroy -nvp". Unlike summing the
+// Used column this accounts for space shared between the snapshots. Runs of
+// snapshots that are adjacent within their dataset are passed as ranges
+// (pool/ds@a%b), so the estimate needs one dry run per dataset.
+func (c *Client) EstimateDestroy(names []string) (uint64, error) {
+	if len(names) == 0 {
+		return 0, nil
+	}
+	all, err := c.ListSnapshots()
+	if err != nil {
+		return 0, err
+	}
+
+	var total uint64
+	for _, spec := range destroySpecs(all, names) {
+		out, err := c.runner.CombinedOutput("zfs", "destroy", "-nvp", spec)
+		if err != nil {
+			return 0, fmt.Errorf(
+				"failed to estimate destroy of %s: %s: %w",
+				spec,
+				strings.TrimSpace(string(out)),
+				err,
+			)
+		}
+		reclaim, ok := parseReclaim(string(out))
+		if !ok {
+			return 0, fmt.Errorf("failed to estimate destroy of %s: no reclaim line in output", spec)
+		}
+		total += reclaim
+	}
+	return total, nil
+}
+
+// destroySpecs builds one "dataset@a%b,c" argument per dataset for the
+// selected names. all must be sorted by creation; a range is only used for
+// snapshots that are adjacent in all, so no unselected snapshot is covered.
+// Names missing from all are passed on individually.
+func destroySpecs(all []Snapshot, names []string) []string {
+	selected := make(map[string]bool, len(names))
+	for _, n := range names {
+		selected[n] = true
+	}
+
+	parts := make(map[string][]string)
+	var datasets []string
+	add := func(dataset, part string) {
+		if _, ok := parts[dataset]; !ok {
+			datasets = append(datasets, dataset)
+		}
+		parts[dataset] = append(parts[dataset], part)
+	}
+
+	// Group the listing by dataset, keeping creation order.
+	byDataset := make(map[string][]Snapshot)
+	var order []string
+	for _, s := range all {
+		if _, ok := byDataset[s.Dataset]; !ok {
+			order = append(order, s.Dataset)
+		}
+		byDataset[s.Dataset] = append(byDataset[s.Dataset], s)
+	}
+
+	for _, dataset := range order {
+		snaps := byDataset[dataset]
+		for i := 0; i < len(snaps); i++ {
+			if !selected[snaps[i].Name] {
+				continue
+			}
+			j := i
+			for j+1 < len(snaps) && selected[snaps[j+1].Name] {
+				j++
+			}
+			if j > i {
+				add(dataset, snaps[i].ShortName+"%"+snaps[j].ShortName)
+			} else {
+				add(dataset, snaps[i].ShortName)
+			}
+			for k := i; k <= j; k++ {
+				delete(selected, snaps[k].Name)
+			}
+			i = j
+		}
+	}
+	for _, n := range names {
+		if !selected[n] {
+			continue
+		}
+		if dataset, short, ok := strings.Cut(n, "@"); ok {
+			add(dataset, short)
+		}
+	}
+
+	sort.Strings(datasets)
+	specs := make([]string, 0, len(datasets))
+	for _, dataset := range datasets {
+		specs = append(specs, dataset+"@"+strings.Join(parts[dataset], ","))
+	}
+	return specs
+}
+
+// parseReclaim extracts the byte count from the "reclaim" line printed by
+// "zfs destroy -nvp".
+func parseReclaim(output string) (uint64, bool) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "reclaim" {
+			v, err := strconv.ParseUint(fields[1], 10, 64)
+			return v, err == nil
+		}
+	}
+	return 0, false
+}
+
+// CloneSnapshot creates the dataset target as a clone of snapshot. props are
+// set on the new dataset via -o, e.g. {"readonly": "on"}.
+func (c *Client) CloneSnapshot(snapshot, target string, props map[string]string) error {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	args := []string{"clone"}
+	for _, k := range keys {
+		args = append(args, "-o", k+"="+props[k])
+	}
+	args = append(args, snapshot, target)
+	return c.runPrivileged(fmt.Sprintf("clone snapshot %q to %q", snapshot, target), args...)
+}
+
+// RollbackItem is a snapshot or bookmark that a recursive rollback destroys.
+type RollbackItem struct {
+	Name string
+	// Clones lists datasets cloned from this snapshot. zfs rollback -r
+	// refuses to destroy a snapshot that has clones.
+	Clones []string
+}
+
+// IsBookmark reports whether the item is a bookmark (dataset#name).
+func (i RollbackItem) IsBookmark() bool {
+	return strings.Contains(i.Name, "#")
+}
+
+// RollbackPreview returns the snapshots and bookmarks of the snapshot's
+// dataset that are newer than the given snapshot, oldest first. These are
+// destroyed when rolling back with recursive set. Bookmarks created in the
+// same transaction group as the snapshot are not newer and are kept.
+func (c *Client) RollbackPreview(snapshot string) ([]RollbackItem, error) {
+	dataset, _, ok := strings.Cut(snapshot, "@")
+	if !ok {
+		return nil, fmt.Errorf("invalid snapshot name %q", snapshot)
+	}
+	out, err := c.runner.Output(
+		"zfs",
+		"list",
+		"-H",
+		"-p",
+		"-t",
+		"snapshot,bookmark",
+		"-o",
+		"name,createtxg,clones",
+		"-s",
+		"createtxg",
+		"-d",
+		"1",
+		dataset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots of %q: %w", dataset, err)
+	}
+
+	var targetTxg uint64
+	found := false
+	type entry struct {
+		item RollbackItem
+		txg  uint64
+	}
+	var entries []entry
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		txg, ok := parseUint(strings.TrimSpace(fields[1]))
+		if !ok {
+			continue
+		}
+		name := strings.TrimSpace(fields[0])
+		if name == snapshot {
+			targetTxg = txg
+			found = true
+			continue
+		}
+		item := RollbackItem{Name: name}
+		if len(fields) >= 3 {
+			item.Clones = splitList(fields[2])
+		}
+		entries = append(entries, entry{item: item, txg: txg})
+	}
+	if !found {
+		return nil, fmt.Errorf("snapshot %q not found", snapshot)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].txg < entries[j].txg })
+
+	var newer []RollbackItem
+	for _, e := range entries {
+		if e.txg > targetTxg {
+			newer = append(newer, e.item)
+		}
+	}
+	return newer, nil
+}
+
+// RollbackSnapshot rolls the snapshot's dataset back to the given snapshot.
+// If recursive is set, newer snapshots and bookmarks are destroyed (zfs
+// rollback -r); otherwise the rollback fails when any exist.
+func (c *Client) RollbackSnapshot(name string, recursive bool) error {
+	args := []string{"rollback"}
+	if recursive {
+		args = append(args, "-r")
+	}
+	args = append(args, name)
+	return c.runPrivileged(fmt.Sprintf("roll back to snapshot %q", name), args...)
+}
+
+// IsRoot reports whether commands run with root privileges.
+func (c *Client) IsRoot() bool {
+	return c.runner.IsRoot()
+}
+
+// runPrivileged runs a modifying zfs command. If it fails with "permission
+// denied" and we are not root, it is retried once via non-interactive sudo.
+// what describes the operation for error messages, e.g. `destroy snapshot "x"`.
+func (c *Client) runPrivileged(what string, args ...string) error {
+	return c.runPrivilegedCommand("zfs", what, args...)
+}
+
+// runPrivilegedCommand is runPrivileged for name, "zfs" or "zpool".
+func (c *Client) runPrivilegedCommand(name, what string, args ...string) error {
+	out, err := c.runner.CombinedOutput(name, args...)
+	if err == nil {
+		return nil
+	}
+	outStr := string(out)
+	if !c.runner.IsRoot() && strings.Contains(strings.ToLower(outStr), "permission denied") {
+		sudoArgs := append([]string{"-n", name}, args...)
+		sudoOut, sudoErr := c.runner.CombinedOutput("sudo", sudoArgs...)
+		if sudoErr == nil {
+			return nil
+		}
+		sudoLower := strings.ToLower(string(sudoOut))
+		if strings.Contains(sudoLower, "a password is required") ||
+			strings.Contains(sudoLower, "interactive authentication is required") {
+			return fmt.Errorf(
+				"failed to %s: permission denied; run zfsguard with sudo or configure NOPASSWD",
+				what,
+			)
+		}
+		return fmt.Errorf("failed to %s: %s: %w", what, string(sudoOut), sudoErr)
+	}
+	return fmt.Errorf("failed to %s: %s: %w", what, outStr, err)
+}
+
 // PoolStatuses returns the status of all ZFS pools.
-func PoolStatuses() ([]PoolStatus, error) {
-	cmd := exec.Command("zpool", "list", "-H", "-o", "name,health")
-	out, err := cmd.Output()
+func (c *Client) PoolStatuses() ([]PoolStatus, error) {
+	if c.jsonSupported() {
+		if statuses, err := c.poolStatusesJSON(); err == nil {
+			return statuses, nil
+		}
+	}
+	out, err := c.runner.Output("zpool", "list", "-H", "-o", "name,health")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list pools: %w", err)
 	}
@@ -135,10 +413,11 @@ func PoolStatuses() ([]PoolStatus, error) {
 				State: fields[1],
 			}
 			// Get detailed status for error info
-			detail, err := poolDetail(fields[0])
+			detail, err := c.poolDetail(fields[0])
 			if err == nil {
-				status.Errors = detail.Errors
-				status.Raw = detail.Raw
+				detail.Name = status.Name
+				detail.State = status.State
+				status = detail
 			}
 			statuses = append(statuses, status)
 		}
@@ -146,31 +425,6 @@ func PoolStatuses() ([]PoolStatus, error) {
 	return statuses, nil
 }
 
-func poolDetail(pool string) (PoolStatus, error) {
-	cmd := exec.Command("zpool", "status", pool)
-	out, err := cmd.Output()
-	if err != nil {
-		return PoolStatus{}, err
-	}
-
-	raw := string(out)
-	status := PoolStatus{Raw: raw}
-
-	scanner := bufio.NewScanner(strings.NewReader(raw))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(line, "errors:") {
-			status.Errors = strings.TrimPrefix(line, "errors:")
-			status.Errors = strings.TrimSpace(status.Errors)
-		}
-		if strings.HasPrefix(line, "state:") {
-			status.State = strings.TrimPrefix(line, "state:")
-			status.State = strings.TrimSpace(status.State)
-		}
-	}
-	return status, nil
-}
-
 func parseSnapshots(output string) ([]Snapshot, error) {
 	var snapshots []Snapshot
 	scanner := bufio.NewScanner(strings.NewReader(output))
@@ -181,54 +435,88 @@ func parseSnapshots(output string) ([]Snapshot, error) {
 		}
 
 		// Fields are tab-separated from -H flag.
-		// name, used, refer are tab fields, but creation is the rest of the line
-		// Format: name\tused\trefer\tcreation_string
-		parts := strings.SplitN(line, "\t", 4)
+		// Format: name\tused\trefer\tcreation_string[\tclones[\tuserrefs]]
+		parts := strings.Split(line, "\t")
 		if len(parts) < 4 {
 			continue
 		}
+		snapshots = append(snapshots, snapshotFromFields(parts))
+	}
+	return snapshots, nil
+}
 
-		name := strings.TrimSpace(parts[0])
-		used := strings.TrimSpace(parts[1])
-		refer := strings.TrimSpace(parts[2])
-		creationStr := strings.TrimSpace(parts[3])
+// snapshotFromFields builds a Snapshot from the name, used, refer, creation
+// and optional clones and userrefs property values.
+func snapshotFromFields(parts []string) Snapshot {
+	name := strings.TrimSpace(parts[0])
+	used := strings.TrimSpace(parts[1])
+	refer := strings.TrimSpace(parts[2])
+	creationStr := strings.TrimSpace(parts[3])
 
-		if parsed, ok := parseUint(used); ok {
-			used = formatBytes(parsed)
-		}
-		if parsed, ok := parseUint(refer); ok {
-			refer = formatBytes(parsed)
-		}
+	var usedBytes, referBytes uint64
+	if parsed, ok := parseUint(used); ok {
+		usedBytes = parsed
+		used = FormatBytes(parsed)
+	}
+	if parsed, ok := parseUint(refer); ok {
+		referBytes = parsed
+		refer = FormatBytes(parsed)
+	}
 
-		// Parse dataset and short name
-		atIdx := strings.Index(name, "@")
-		var dataset, shortName string
-		if atIdx >= 0 {
-			dataset = name[:atIdx]
-			shortName = name[atIdx+1:]
-		} else {
-			dataset = name
-			shortName = name
-		}
+	// Parse dataset and short name
+	atIdx := strings.Index(name, "@")
+	var dataset, shortName string
+	if atIdx >= 0 {
+		dataset = name[:atIdx]
+		shortName = name[atIdx+1:]
+	} else {
+		dataset = name
+		shortName = name
+	}
 
-		// Parse creation time - ZFS outputs like "Mon Jan  2 15:04 2006"
-		var creation time.Time
-		if parsed, ok := parseInt64(creationStr); ok {
-			creation = time.Unix(parsed, 0)
-		} else {
-			creation, _ = parseZFSTime(creationStr)
+	// Parse creation time - ZFS outputs like "Mon Jan  2 15:04 2006"
+	var creation time.Time
+	if parsed, ok := parseInt64(creationStr); ok {
+		creation = time.Unix(parsed, 0)
+	} else {
+		creation, _ = parseZFSTime(creationStr)
+	}
+
+	var clones []string
+	if len(parts) >= 5 {
+		clones = splitList(parts[4])
+	}
+	var userRefs int
+	if len(parts) >= 6 {
+		if parsed, ok := parseInt64(strings.TrimSpace(parts[5])); ok {
+			userRefs = int(parsed)
 		}
+	}
 
-		snapshots = append(snapshots, Snapshot{
-			Name:      name,
-			Dataset:   dataset,
-			ShortName: shortName,
-			Used:      used,
-			Refer:     refer,
-			Creation:  creation,
-		})
+	return Snapshot{
+		Name:       name,
+		Dataset:    dataset,
+		ShortName:  shortName,
+		Used:       used,
+		Refer:      refer,
+		UsedBytes:  usedBytes,
+		ReferBytes: referBytes,
+		Creation:   creation,
+		Clones:     clones,
+		UserRefs:   userRefs,
 	}
-	return snapshots, nil
+}
+
+// splitList splits a comma-separated property value such as clones.
+// ZFS prints "-" or an empty value when the list is empty.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(strings.TrimSpace(value), ",") {
+		if item != "" && item != "-" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 func parseZFSTime(s string) (time.Time, error) {
@@ -269,11 +557,12 @@ func parseInt64(value string) (int64, bool) {
 	return parsed, true
 }
 
-func formatBytes(value uint64) string {
+// FormatBytes renders a byte count the way zfs list does, e.g. 1.5K or 12G.
+func FormatBytes(value uint64) string {
 	if value < 1024 {
 		return fmt.Sprintf("%dB", value)
 	}
-	units := []string{"K", "M", "G", "T", "P", "E"}
+	units := []string{"B", "K", "M", "G", "T", "P", "E"}
 	val := float64(value)
 	idx := 0
 	for val >= 1024 && idx < len(units)-1 {
@@ -286,13 +575,9 @@ func formatBytes(value uint64) string {
 	return fmt.Sprintf("%.1f%s", val, units[idx])
 }
 
-func isRoot() bool {
-	return os.Geteuid() == 0
-}
-
 // CheckZFSErrors checks all pools for errors and returns a summary.
-func CheckZFSErrors() (hasErrors bool, summary string, err error) {
-	statuses, err := PoolStatuses()
+func (c *Client) CheckZFSErrors() (hasErrors bool, summary string, err error) {
+	statuses, err := c.PoolStatuses()
 	if err != nil {
 		return false, "", err
 	}
@@ -302,6 +587,9 @@ func CheckZFSErrors() (hasErrors bool, summary string, err error) {
 		if s.State != "ONLINE" {
 			issues = append(issues, fmt.Sprintf("Pool %q is in state: %s", s.Name, s.State))
 		}
+		for _, d := range s.FaultedDevices() {
+			issues = append(issues, fmt.Sprintf("Pool %q device %s", s.Name, d.Describe()))
+		}
 		if s.Errors != "" && s.Errors != "No known data errors" {
 			issues = append(issues, fmt.Sprintf("Pool %q has errors: %s", s.Name, s.Errors))
 		}
@@ -0,0 +1,366 @@
+package zfs_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbek/zfsguard/internal/zfs"
+	"github.com/pbek/zfsguard/internal/zfs/zfstest"
+)
+
+func scenario(t *testing.T, name string) (*zfs.Client, *zfstest.Runner) {
+	t.Helper()
+	r, err := zfstest.Scenario(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zfs.NewClient(r), r
+}
+
+func TestListSnapshots(t *testing.T) {
+	c, _ := scenario(t, "healthy")
+
+	snaps, err := c.ListSnapshots()
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	if len(snaps) != 3 {
+		t.Fatalf("got %d snapshots, want 3", len(snaps))
+	}
+	s := snaps[0]
+	if s.Name != "tank/data@zfsguard_2026-02-01" || s.Dataset != "tank/data" ||
+		s.ShortName != "zfsguard_2026-02-01" {
+		t.Errorf("unexpected snapshot names: %+v", s)
+	}
+	if !s.Creation.Equal(time.Unix(1769904000, 0)) {
+		t.Errorf("Creation = %v", s.Creation)
+	}
+}
+
+func TestCheckZFSErrors(t *testing.T) {
+	tests := []struct {
+		scenario  string
+		hasErrors bool
+		summary   string
+	}{
+		{"healthy", false, "All pools healthy"},
+		{"degraded", true, `Pool "tank" is in state: DEGRADED` + "\n" +
+			`Pool "tank" device sdb FAULTED (read 3, write 120, cksum 0: too many errors)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scenario, func(t *testing.T) {
+			c, _ := scenario(t, tt.scenario)
+			hasErrors, summary, err := c.CheckZFSErrors()
+			if err != nil {
+				t.Fatalf("CheckZFSErrors: %v", err)
+			}
+			if hasErrors != tt.hasErrors || summary != tt.summary {
+				t.Errorf("got (%v, %q), want (%v, %q)", hasErrors, summary, tt.hasErrors, tt.summary)
+			}
+		})
+	}
+}
+
+func TestPoolStatusesDegraded(t *testing.T) {
+	c, _ := scenario(t, "degraded")
+
+	pools, err := c.PoolStatuses()
+	if err != nil {
+		t.Fatalf("PoolStatuses: %v", err)
+	}
+	if len(pools) != 1 {
+		t.Fatalf("got %d pools, want 1", len(pools))
+	}
+	p := pools[0]
+	if p.Name != "tank" || p.State != "DEGRADED" || p.Errors != "No known data errors" {
+		t.Errorf("unexpected pool status: %+v", p)
+	}
+	if !strings.Contains(p.Raw, "FAULTED") {
+		t.Error("Raw does not contain the zpool status output")
+	}
+}
+
+func TestDestroySnapshot(t *testing.T) {
+	c, r := scenario(t, "healthy")
+
+	if err := c.DestroySnapshot("tank/data@zfsguard_2026-02-01"); err != nil {
+		t.Fatalf("DestroySnapshot: %v", err)
+	}
+	if !r.Called("zfs destroy tank/data@zfsguard_2026-02-01") {
+		t.Errorf("zfs destroy not called: %q", r.Calls())
+	}
+}
+
+func TestDestroySnapshotPermissionDenied(t *testing.T) {
+	const snap = "tank/data@zfsguard_2026-02-01"
+
+	t.Run("non-root retries with sudo", func(t *testing.T) {
+		c, r := scenario(t, "permission-denied")
+		err := c.DestroySnapshot(snap)
+		if err == nil || !strings.Contains(err.Error(), "configure NOPASSWD") {
+			t.Fatalf("err = %v, want sudo hint", err)
+		}
+		if !r.Called("sudo -n zfs destroy " + snap) {
+			t.Errorf("sudo retry not attempted: %q", r.Calls())
+		}
+	})
+
+	t.Run("root does not retry", func(t *testing.T) {
+		c, r := scenario(t, "permission-denied")
+		r.SetRoot(true)
+		err := c.DestroySnapshot(snap)
+		if err == nil || !strings.Contains(err.Error(), "permission denied") {
+			t.Fatalf("err = %v, want permission denied", err)
+		}
+		if r.Called("sudo -n zfs destroy " + snap) {
+			t.Error("sudo retry attempted as root")
+		}
+	})
+}
+
+func TestCreateSnapshotPermissionDenied(t *testing.T) {
+	c, _ := scenario(t, "permission-denied")
+	err := c.CreateSnapshot("tank/data@manual", false)
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("err = %v, want permission denied", err)
+	}
+}
+
+func TestRollbackPreview(t *testing.T) {
+	c, _ := scenario(t, "healthy")
+
+	newer, err := c.RollbackPreview("tank/data@zfsguard_2026-02-01")
+	if err != nil {
+		t.Fatalf("RollbackPreview: %v", err)
+	}
+	// The bookmark created in the same txg as the target is kept.
+	want := []string{"tank/data@zfsguard_2026-02-08", "tank/data#replication"}
+	if len(newer) != len(want) {
+		t.Fatalf("newer = %+v, want %q", newer, want)
+	}
+	for i := range want {
+		if newer[i].Name != want[i] {
+			t.Errorf("newer[%d] = %q, want %q", i, newer[i].Name, want[i])
+		}
+	}
+	if !newer[1].IsBookmark() || newer[0].IsBookmark() {
+		t.Error("IsBookmark mismatch")
+	}
+
+	newer, err = c.RollbackPreview("tank/data@zfsguard_2026-02-08")
+	if err != nil {
+		t.Fatalf("RollbackPreview: %v", err)
+	}
+	if len(newer) != 0 {
+		t.Errorf("newer = %+v, want none", newer)
+	}
+
+	if _, err := c.RollbackPreview("tank/data@missing"); err == nil {
+		t.Error("expected error for unknown snapshot")
+	}
+	if _, err := c.RollbackPreview("tank/data"); err == nil {
+		t.Error("expected error for dataset name")
+	}
+}
+
+func TestRollbackPreviewSortsAndReportsClones(t *testing.T) {
+	r := zfstest.New()
+	r.Set("zfs list -H -p -t snapshot,bookmark -o name,createtxg,clones -s createtxg -d 1 tank",
+		zfstest.Response{Stdout: "tank@c\t30\ttank/restore,tank/other\n" +
+			"tank@a\t10\t-\n" +
+			"tank#b\t20\t\n"})
+	c := zfs.NewClient(r)
+
+	newer, err := c.RollbackPreview("tank@a")
+	if err != nil {
+		t.Fatalf("RollbackPreview: %v", err)
+	}
+	if len(newer) != 2 || newer[0].Name != "tank#b" || newer[1].Name != "tank@c" {
+		t.Fatalf("newer = %+v, want [tank#b tank@c]", newer)
+	}
+	if len(newer[0].Clones) != 0 {
+		t.Errorf("bookmark clones = %q", newer[0].Clones)
+	}
+	if got := strings.Join(newer[1].Clones, ","); got != "tank/restore,tank/other" {
+		t.Errorf("clones = %q", got)
+	}
+}
+
+func TestRollbackSnapshot(t *testing.T) {
+	tests := []struct {
+		snapshot  string
+		recursive bool
+		command   string
+	}{
+		{"tank/data@zfsguard_2026-02-01", true, "zfs rollback -r tank/data@zfsguard_2026-02-01"},
+		{"tank/data@zfsguard_2026-02-08", false, "zfs rollback tank/data@zfsguard_2026-02-08"},
+	}
+	for _, tt := range tests {
+		c, r := scenario(t, "healthy")
+		if err := c.RollbackSnapshot(tt.snapshot, tt.recursive); err != nil {
+			t.Fatalf("RollbackSnapshot(%q, %v): %v", tt.snapshot, tt.recursive, err)
+		}
+		if calls := r.Calls(); len(calls) != 1 || calls[0] != tt.command {
+			t.Errorf("calls = %q, want [%q]", calls, tt.command)
+		}
+	}
+}
+
+func TestListSnapshotsClones(t *testing.T) {
+	c, _ := scenario(t, "healthy")
+
+	snaps, err := c.ListSnapshots()
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	clones := map[string]string{}
+	for _, s := range snaps {
+		clones[s.Name] = strings.Join(s.Clones, ",")
+	}
+	if clones["tank/home@zfsguard_2026-02-01"] != "tank/home-restore" {
+		t.Errorf("clones of tank/home@zfsguard_2026-02-01 = %q", clones["tank/home@zfsguard_2026-02-01"])
+	}
+	if clones["tank/data@zfsguard_2026-02-01"] != "" {
+		t.Errorf("clones of tank/data@zfsguard_2026-02-01 = %q, want none",
+			clones["tank/data@zfsguard_2026-02-01"])
+	}
+}
+
+func TestCloneSnapshot(t *testing.T) {
+	r := zfstest.New()
+	r.Set("zfs clone -o mountpoint=/mnt/restore -o readonly=on tank/data@a tank/restore",
+		zfstest.Response{})
+	c := zfs.NewClient(r)
+
+	props := map[string]string{"readonly": "on", "mountpoint": "/mnt/restore"}
+	if err := c.CloneSnapshot("tank/data@a", "tank/restore", props); err != nil {
+		t.Fatalf("CloneSnapshot: %v (calls %q)", err, r.Calls())
+	}
+
+	r.Set("zfs clone tank/data@a tank/exists",
+		zfstest.Response{Stderr: "cannot create 'tank/exists': dataset already exists\n", ExitCode: 1})
+	err := c.CloneSnapshot("tank/data@a", "tank/exists", nil)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("err = %v, want dataset already exists", err)
+	}
+}
+
+func TestHolds(t *testing.T) {
+	c, _ := scenario(t, "healthy")
+
+	snaps, err := c.ListSnapshots()
+	if err != nil {
+		t.Fatalf("ListSnapshots: %v", err)
+	}
+	var held []string
+	for _, s := range snaps {
+		if s.UserRefs > 0 {
+			held = append(held, s.Name)
+		}
+	}
+	if len(held) != 1 || held[0] != "tank/data@zfsguard_2026-02-08" {
+		t.Fatalf("held snapshots = %q", held)
+	}
+
+	holds, err := c.Holds(held...)
+	if err != nil {
+		t.Fatalf("Holds: %v", err)
+	}
+	if len(holds) != 1 {
+		t.Fatalf("got %d holds, want 1", len(holds))
+	}
+	h := holds[0]
+	if h.Snapshot != held[0] || h.Tag != "keep" || h.Created.Unix() != 1770509000 {
+		t.Errorf("hold = %+v", h)
+	}
+
+	if holds, err := c.Holds(); err != nil || holds != nil {
+		t.Errorf("Holds() = %v, %v; want nil, nil", holds, err)
+	}
+}
+
+func TestHoldAndRelease(t *testing.T) {
+	c, r := scenario(t, "healthy")
+
+	if err := c.Hold("zfsguard", "tank/data@zfsguard_2026-02-01"); err != nil {
+		t.Fatalf("Hold: %v", err)
+	}
+	if err := c.Release("zfsguard", "tank/data@zfsguard_2026-02-01"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	want := []string{
+		"zfs hold zfsguard tank/data@zfsguard_2026-02-01",
+		"zfs release zfsguard tank/data@zfsguard_2026-02-01",
+	}
+	if calls := r.Calls(); strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+
+	err := c.Release("zfsguard", "tank/data@zfsguard_2026-02-08")
+	if err == nil || !strings.Contains(err.Error(), "no such tag") {
+		t.Errorf("err = %v, want no such tag", err)
+	}
+}
+
+func TestEstimateDestroy(t *testing.T) {
+	r := zfstest.New()
+	r.Set("zfs list -t snapshot -H -o name,used,refer,creation,clones,userrefs -p -s creation",
+		zfstest.Response{Stdout: "tank/data@a\t1\t1\t1\t-\t0\n" +
+			"tank/data@b\t1\t1\t2\t-\t0\n" +
+			"tank/home@a\t1\t1\t2\t-\t0\n" +
+			"tank/data@c\t1\t1\t3\t-\t0\n" +
+			"tank/data@d\t1\t1\t4\t-\t0\n" +
+			"tank/data@e\t1\t1\t5\t-\t0\n"})
+	r.Set("zfs destroy -nvp tank/data@a%b,d%e", zfstest.Response{
+		Stdout: "destroy\ttank/data@a\ndestroy\ttank/data@b\ndestroy\ttank/data@d\n" +
+			"destroy\ttank/data@e\nreclaim\t3072\n",
+	})
+	r.Set("zfs destroy -nvp tank/home@a", zfstest.Response{
+		Stdout: "destroy\ttank/home@a\nreclaim\t1024\n",
+	})
+	c := zfs.NewClient(r)
+
+	// c is not selected, so it must split the data snapshots into two ranges.
+	got, err := c.EstimateDestroy([]string{"tank/data@e", "tank/home@a", "tank/data@a", "tank/data@b", "tank/data@d"})
+	if err != nil {
+		t.Fatalf("EstimateDestroy: %v (calls %q)", err, r.Calls())
+	}
+	if got != 4096 {
+		t.Errorf("reclaim = %d, want 4096", got)
+	}
+}
+
+func TestEstimateDestroyDependentClones(t *testing.T) {
+	c, _ := scenario(t, "healthy")
+
+	got, err := c.EstimateDestroy([]string{"tank/data@zfsguard_2026-02-01"})
+	if err != nil || got != 1048576 {
+		t.Errorf("EstimateDestroy = %d, %v; want 1048576", got, err)
+	}
+
+	_, err = c.EstimateDestroy([]string{"tank/home@zfsguard_2026-02-01"})
+	if err == nil || !strings.Contains(err.Error(), "dependent clones") {
+		t.Errorf("err = %v, want dependent clones", err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		value uint64
+		want  string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1.0K"},
+		{1536, "1.5K"},
+		{52428800, "50M"},
+		{4294967296, "4.0G"},
+		{1 << 50, "1.0P"},
+	}
+	for _, tt := range tests {
+		if got := zfs.FormatBytes(tt.value); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
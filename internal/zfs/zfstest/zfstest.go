@@ -0,0 +1,208 @@
+// Package zfstest provides a scripted fake zfs.Runner that replays recorded
+// zfs, zpool and smartctl output and records every invocation, so the zfs,
+// monitor and tui packages can be exercised without real pools or disks.
+//
+// Responses are keyed by the full command line (name and arguments joined by
+// single spaces). They can be registered directly with Set or loaded from
+// YAML fixtures:
+//
+//	# fixtures/example.yaml
+//	- command: zpool list -H -o name,health
+//	  stdout: "tank\tDEGRADED\n"
+//	- command: zfs destroy tank@old
+//	  stderr: "cannot destroy snapshot tank@old: permission denied\n"
+//	  exit_code: 1
+//
+// A set of recorded scenarios ships with the package and can be loaded with
+// Scenario. Each one answers the read-only commands used by the TUI and the
+// monitor (snapshot and dataset listing, zpool list/status, smartctl --scan
+// and the per-disk SMART check for every scanned device):
+//
+//   - "healthy": one ONLINE mirror, two passing disks and three snapshots;
+//     also answers create, destroy (including dry runs), rollback, clone,
+//     hold and release of its snapshots; one snapshot carries a "keep" hold
+//   - "healthy-json": the same system on OpenZFS 2.3, answering the JSON
+//     (-j) variants of the listing and status commands
+//   - "degraded": the same mirror with a FAULTED member
+//   - "smart-failed": an ONLINE pool on top of a disk whose SMART
+//     self-assessment FAILED
+//   - "smart-worn": an ATA, an NVMe and a SCSI disk that pass the
+//     self-assessment but report reallocated sectors, wear and defects
+//   - "permission-denied": an unprivileged user; listing works, zfs snapshot,
+//     zfs destroy and the sudo retry are rejected and smartctl cannot open
+//     the disk
+//
+// Scenarios without a "zfs version -j" response behave like OpenZFS before
+// 2.3, so the zfs package uses its text parsers.
+//
+// The fake reports a non-root user by default, so modifying commands that
+// fail with "permission denied" are retried through sudo; use SetRoot to
+// change that.
+package zfstest
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed fixtures/*.yaml
+var fixtures embed.FS
+
+// Response is the recorded result of a single command.
+type Response struct {
+	Stdout   string `yaml:"stdout"`
+	Stderr   string `yaml:"stderr"`
+	ExitCode int    `yaml:"exit_code"`
+}
+
+// Fixture binds a command line to its recorded response.
+type Fixture struct {
+	Command  string `yaml:"command"`
+	Response `yaml:",inline"`
+}
+
+// ExitError is returned for responses with a non-zero exit code, mirroring
+// *exec.ExitError.
+type ExitError struct {
+	Code   int
+	Stderr string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Runner is a fake zfs.Runner. It is safe for concurrent use.
+type Runner struct {
+	mu        sync.Mutex
+	responses map[string]Response
+	calls     []string
+	root      bool
+}
+
+// New creates an empty Runner. Commands without a registered response fail
+// with exit code 127, as if the binary was not installed.
+func New() *Runner {
+	return &Runner{responses: make(map[string]Response)}
+}
+
+// Scenario creates a Runner preloaded with one of the bundled fixture
+// scenarios, e.g. "healthy", "degraded", "smart-failed" or
+// "permission-denied".
+func Scenario(name string) (*Runner, error) {
+	data, err := fixtures.ReadFile("fixtures/" + name + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("unknown scenario %q: %w", name, err)
+	}
+	r := New()
+	if err := r.Load(data); err != nil {
+		return nil, fmt.Errorf("failed to load scenario %q: %w", name, err)
+	}
+	return r, nil
+}
+
+// LoadFile adds the fixtures from the YAML file at path.
+func (r *Runner) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read fixtures %s: %w", path, err)
+	}
+	return r.Load(data)
+}
+
+// Load adds the fixtures from YAML data. Later fixtures replace earlier ones
+// for the same command.
+func (r *Runner) Load(data []byte) error {
+	var list []Fixture
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("failed to parse fixtures: %w", err)
+	}
+	for _, f := range list {
+		r.Set(f.Command, f.Response)
+	}
+	return nil
+}
+
+// Set registers the response for a command line.
+func (r *Runner) Set(command string, resp Response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses[strings.TrimSpace(command)] = resp
+}
+
+// SetRoot sets whether the fake pretends to run commands as root.
+func (r *Runner) SetRoot(root bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.root = root
+}
+
+// IsRoot implements zfs.Runner.
+func (r *Runner) IsRoot() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.root
+}
+
+// Calls returns the command lines invoked so far, in order.
+func (r *Runner) Calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+// Called reports whether the given command line has been invoked.
+func (r *Runner) Called(command string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.calls {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset forgets all recorded invocations but keeps the responses.
+func (r *Runner) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
+
+// Output implements zfs.Runner.
+func (r *Runner) Output(name string, args ...string) ([]byte, error) {
+	resp, err := r.run(name, args)
+	return []byte(resp.Stdout), err
+}
+
+// CombinedOutput implements zfs.Runner.
+func (r *Runner) CombinedOutput(name string, args ...string) ([]byte, error) {
+	resp, err := r.run(name, args)
+	return []byte(resp.Stdout + resp.Stderr), err
+}
+
+func (r *Runner) run(name string, args []string) (Response, error) {
+	command := strings.Join(append([]string{name}, args...), " ")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, command)
+
+	resp, ok := r.responses[command]
+	if !ok {
+		resp = Response{
+			Stderr:   fmt.Sprintf("zfstest: no fixture for %q\n", command),
+			ExitCode: 127,
+		}
+	}
+	if resp.ExitCode != 0 {
+		return resp, &ExitError{Code: resp.ExitCode, Stderr: resp.Stderr}
+	}
+	return resp, nil
+}
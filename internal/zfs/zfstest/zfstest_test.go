@@ -0,0 +1,98 @@
+package zfstest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pbek/zfsguard/internal/zfs"
+)
+
+var _ zfs.Runner = (*Runner)(nil)
+
+func TestScenariosLoad(t *testing.T) {
+	for _, name := range []string{"healthy", "degraded", "smart-failed", "permission-denied"} {
+		r, err := Scenario(name)
+		if err != nil {
+			t.Fatalf("Scenario(%q): %v", name, err)
+		}
+		if _, err := r.Output("zpool", "list", "-H", "-o", "name,health"); err != nil {
+			t.Errorf("%s: zpool list failed: %v", name, err)
+		}
+	}
+}
+
+func TestScenarioUnknown(t *testing.T) {
+	if _, err := Scenario("does-not-exist"); err == nil {
+		t.Fatal("expected error for unknown scenario")
+	}
+}
+
+func TestRunnerRecordsCalls(t *testing.T) {
+	r := New()
+	r.Set("zfs list -H -o name", Response{Stdout: "tank\n"})
+
+	out, err := r.Output("zfs", "list", "-H", "-o", "name")
+	if err != nil {
+		t.Fatalf("Output: %v", err)
+	}
+	if string(out) != "tank\n" {
+		t.Errorf("Output = %q, want %q", out, "tank\n")
+	}
+	if _, err := r.Output("zpool", "status"); err == nil {
+		t.Error("expected error for command without fixture")
+	}
+
+	want := []string{"zfs list -H -o name", "zpool status"}
+	got := r.Calls()
+	if len(got) != len(want) {
+		t.Fatalf("Calls() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Calls()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if !r.Called("zpool status") {
+		t.Error("Called(zpool status) = false")
+	}
+
+	r.Reset()
+	if len(r.Calls()) != 0 {
+		t.Errorf("Calls() after Reset = %q", r.Calls())
+	}
+}
+
+func TestRunnerExitCode(t *testing.T) {
+	r := New()
+	r.Set("zfs destroy tank@a", Response{Stdout: "out\n", Stderr: "denied\n", ExitCode: 1})
+
+	out, err := r.Output("zfs", "destroy", "tank@a")
+	if string(out) != "out\n" {
+		t.Errorf("Output = %q, want stdout only", out)
+	}
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 || exitErr.Stderr != "denied\n" {
+		t.Fatalf("err = %#v, want ExitError{Code: 1}", err)
+	}
+
+	out, _ = r.CombinedOutput("zfs", "destroy", "tank@a")
+	if string(out) != "out\ndenied\n" {
+		t.Errorf("CombinedOutput = %q", out)
+	}
+
+	_, err = r.Output("missing")
+	if !errors.As(err, &exitErr) || exitErr.Code != 127 {
+		t.Errorf("missing fixture err = %#v, want exit code 127", err)
+	}
+}
+
+func TestRunnerRoot(t *testing.T) {
+	r := New()
+	if r.IsRoot() {
+		t.Error("IsRoot() = true by default")
+	}
+	r.SetRoot(true)
+	if !r.IsRoot() {
+		t.Error("IsRoot() = false after SetRoot(true)")
+	}
+}